@@ -142,18 +142,51 @@ func (d *dumper) dumpFile(f string, data interface{}) {
 		log.Fatal(err)
 	}
 }
+
+// hash identifies the input by its fields that are set. The unset fields are
+// left out, so that the fields added by newer versions of aws sdk don't change
+// the hash.
 func (d *dumper) hash(input interface{}) string {
 	js, err := json.Marshal(input)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	jsonStr := d.rr.replace(d.scenarioID, string(js))
-	buf := md5.Sum([]byte(jsonStr))
+	var fields interface{}
+	if err = json.Unmarshal([]byte(d.rr.replace(d.scenarioID, string(js))), &fields); err != nil {
+		log.Fatal(err)
+	}
+
+	js, err = json.Marshal(withoutNulls(fields))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	buf := md5.Sum(js)
 
 	return hex.EncodeToString(buf[:])
 }
 
+func withoutNulls(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, field := range val {
+			if field == nil {
+				delete(val, k)
+				continue
+			}
+
+			val[k] = withoutNulls(field)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = withoutNulls(item)
+		}
+	}
+
+	return v
+}
+
 func (d *dumper) addReplacement(from, to string) {
 	r, ok := d.rr[d.scenarioID]
 
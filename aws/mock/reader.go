@@ -55,7 +55,11 @@ func (c *GfCloudFormation) ValidateTemplate(input *clf.ValidateTemplateInput) (*
 	return output, err
 }
 
-func (c *GfCloudFormation) CreateChangeSet(input *clf.CreateChangeSetInput) (*clf.CreateChangeSetOutput, error) {
+func (c *GfCloudFormation) CreateChangeSetWithContext(
+	_ awssdk.Context,
+	input *clf.CreateChangeSetInput,
+	_ ...request.Option,
+) (*clf.CreateChangeSetOutput, error) {
 	c.dumper.addReplacement(awssdk.StringValue(input.ChangeSetName), "CHST_ID")
 
 	output := &clf.CreateChangeSetOutput{}
@@ -136,11 +140,31 @@ func (c *GfCloudFormation) DeleteStack(input *clf.DeleteStackInput) (*clf.Delete
 	return output, c.dumper.read("DeleteStack", input, output)
 }
 
+func (c *GfCloudFormation) DeleteChangeSet(input *clf.DeleteChangeSetInput) (*clf.DeleteChangeSetOutput, error) {
+	output := &clf.DeleteChangeSetOutput{}
+	return output, c.dumper.read("DeleteChangeSet", input, output)
+}
+
+func (c *GfCloudFormation) ListImports(input *clf.ListImportsInput) (*clf.ListImportsOutput, error) {
+	output := &clf.ListImportsOutput{}
+	return output, c.dumper.read("ListImports", input, output)
+}
+
 func (c *GfCloudFormation) SetStackPolicy(input *clf.SetStackPolicyInput) (*clf.SetStackPolicyOutput, error) {
 	output := &clf.SetStackPolicyOutput{}
 	return output, c.dumper.read("SetStackPolicy", input, output)
 }
 
+func (c *GfCloudFormation) GetStackPolicy(input *clf.GetStackPolicyInput) (*clf.GetStackPolicyOutput, error) {
+	output := &clf.GetStackPolicyOutput{}
+	return output, c.dumper.read("GetStackPolicy", input, output)
+}
+
+func (c *GfCloudFormation) UpdateStack(input *clf.UpdateStackInput) (*clf.UpdateStackOutput, error) {
+	output := &clf.UpdateStackOutput{}
+	return output, c.dumper.read("UpdateStack", input, output)
+}
+
 func (c *GfCloudFormation) GetTemplate(input *clf.GetTemplateInput) (*clf.GetTemplateOutput, error) {
 	output := &clf.GetTemplateOutput{}
 	return output, c.dumper.read("GetTemplate", input, output)
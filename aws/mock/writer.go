@@ -69,8 +69,12 @@ func (c *CloudFormation) ValidateTemplate(input *clf.ValidateTemplateInput) (*cl
 	return output, err
 }
 
-func (c *CloudFormation) CreateChangeSet(input *clf.CreateChangeSetInput) (*clf.CreateChangeSetOutput, error) {
-	output, err := c.realCF.CreateChangeSet(input)
+func (c *CloudFormation) CreateChangeSetWithContext(
+	ctx awssdk.Context,
+	input *clf.CreateChangeSetInput,
+	opts ...request.Option,
+) (*clf.CreateChangeSetOutput, error) {
+	output, err := c.realCF.CreateChangeSetWithContext(ctx, input, opts...)
 
 	c.dumper.addReplacement(awssdk.StringValue(input.ChangeSetName), "CHST_ID")
 	c.dumper.dump("CreateChangeSet", input, output, err)
@@ -164,6 +168,20 @@ func (c *CloudFormation) WaitUntilStackDeleteCompleteWithContext(
 	return err
 }
 
+func (c *CloudFormation) ListImports(input *clf.ListImportsInput) (*clf.ListImportsOutput, error) {
+	output, err := c.realCF.ListImports(input)
+	c.dumper.dump("ListImports", input, output, err)
+
+	return output, err
+}
+
+func (c *CloudFormation) DeleteChangeSet(input *clf.DeleteChangeSetInput) (*clf.DeleteChangeSetOutput, error) {
+	output, err := c.realCF.DeleteChangeSet(input)
+	c.dumper.dump("DeleteChangeSet", input, output, err)
+
+	return output, err
+}
+
 func (c *CloudFormation) SetStackPolicy(input *clf.SetStackPolicyInput) (*clf.SetStackPolicyOutput, error) {
 	output, err := c.realCF.SetStackPolicy(input)
 	c.dumper.dump("SetStackPolicy", input, output, err)
@@ -171,6 +189,20 @@ func (c *CloudFormation) SetStackPolicy(input *clf.SetStackPolicyInput) (*clf.Se
 	return output, err
 }
 
+func (c *CloudFormation) GetStackPolicy(input *clf.GetStackPolicyInput) (*clf.GetStackPolicyOutput, error) {
+	output, err := c.realCF.GetStackPolicy(input)
+	c.dumper.dump("GetStackPolicy", input, output, err)
+
+	return output, err
+}
+
+func (c *CloudFormation) UpdateStack(input *clf.UpdateStackInput) (*clf.UpdateStackOutput, error) {
+	output, err := c.realCF.UpdateStack(input)
+	c.dumper.dump("UpdateStack", input, output, err)
+
+	return output, err
+}
+
 func (c *CloudFormation) GetTemplate(input *clf.GetTemplateInput) (*clf.GetTemplateOutput, error) {
 	output, err := c.realCF.GetTemplate(input)
 	c.dumper.dump("GetTemplate", input, output, err)
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"io/ioutil"
+	"net/url"
 	"strings"
 	"time"
 
@@ -107,6 +109,63 @@ func (s *S3Uploader) Upload(body string) (string, error) {
 	return r.Location, nil
 }
 
+// Download returns the contents of the object referred by the s3 url, e.g. the
+// url of the template.
+func (s *S3Uploader) Download(objectURL string) (string, error) {
+	bucket, key, err := parseS3URL(objectURL)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := s.s3.GetObject(&s3.GetObjectInput{Bucket: &bucket, Key: &key})
+	if err != nil {
+		return "", &BucketError{Op: "download template from s3", Bucket: bucket, Err: err}
+	}
+
+	defer out.Body.Close()
+
+	buf, err := ioutil.ReadAll(out.Body)
+	if err != nil {
+		return "", &BucketError{Op: "download template from s3", Bucket: bucket, Err: err}
+	}
+
+	return string(buf), nil
+}
+
+// parseS3URL extracts the bucket and the key from both virtual-hosted-style
+// (https://bucket.s3.region.amazonaws.com/key) and path-style
+// (https://s3.region.amazonaws.com/bucket/key) urls.
+func parseS3URL(objectURL string) (string, string, error) {
+	u, err := url.Parse(objectURL)
+	if err != nil {
+		return "", "", err
+	}
+
+	host := u.Hostname()
+	path := strings.TrimPrefix(u.Path, "/")
+
+	var bucket, key string
+
+	switch {
+	case u.Scheme == "s3":
+		bucket, key = host, path
+	case strings.HasPrefix(host, "s3.") || strings.HasPrefix(host, "s3-"):
+		parts := strings.SplitN(path, "/", 2)
+		if len(parts) == 2 {
+			bucket, key = parts[0], parts[1]
+		}
+	case strings.Contains(host, ".s3.") || strings.Contains(host, ".s3-"):
+		bucket = host[:strings.Index(host, ".s3")]
+		key = path
+	}
+
+	if bucket == "" || key == "" {
+		return "", "", fmt.Errorf("%s is not a url of s3 object", objectURL)
+	}
+
+	return bucket, key, nil
+}
+
 func (s S3Uploader) Cleanup() error {
 	if s.autoGeneratedBucket == "" {
 		return nil
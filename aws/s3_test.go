@@ -0,0 +1,34 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseS3URL(t *testing.T) {
+	cases := []struct {
+		url    string
+		bucket string
+		key    string
+	}{
+		{"https://tpls.s3.amazonaws.com/stacks/app.yml", "tpls", "stacks/app.yml"},
+		{"https://tpls.s3.eu-west-1.amazonaws.com/app.yml", "tpls", "app.yml"},
+		{"https://tpls.s3-eu-west-1.amazonaws.com/app.yml", "tpls", "app.yml"},
+		{"https://s3.amazonaws.com/tpls/stacks/app.yml", "tpls", "stacks/app.yml"},
+		{"https://s3.eu-west-1.amazonaws.com/tpls/app.yml", "tpls", "app.yml"},
+		{"s3://tpls/app.yml", "tpls", "app.yml"},
+	}
+
+	for _, c := range cases {
+		bucket, key, err := parseS3URL(c.url)
+		assert.NoError(t, err, c.url)
+		assert.Equal(t, c.bucket, bucket, c.url)
+		assert.Equal(t, c.key, key, c.url)
+	}
+
+	for _, u := range []string{"https://example.com/app.yml", "https://s3.amazonaws.com/tpls"} {
+		_, _, err := parseS3URL(u)
+		assert.Error(t, err, u)
+	}
+}
@@ -1,10 +1,13 @@
 package awscf
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -16,20 +19,108 @@ import (
 
 type ChangeSet struct {
 	stack      *Stack
+	name       string
 	body       string
 	url        string
 	parameters map[string]string
 	tags       map[string]string
 
+	// logical ID of the resource to import -> identifier of the resource
+	resourcesToImport map[string]map[string]string
+
+	creationOpts        CreationOptions
+	includeNestedStacks bool
+
 	input cloudformation.CreateChangeSetInput
 }
 
+// CreationOptions are the options applied only when the stack is created.
+type CreationOptions struct {
+	// WaitTimeoutInMinutes limits how long the creation of the stack is
+	// waited for. Change sets don't support the creation timeout, so the
+	// stack keeps being created by CloudFormation after the wait times out.
+	WaitTimeoutInMinutes int64
+
+	// OnFailure is ROLLBACK, DELETE or DO_NOTHING.
+	OnFailure string
+
+	// DisableRollback is the same as OnFailure set to DO_NOTHING.
+	DisableRollback bool
+}
+
+func (o CreationOptions) onStackFailure() string {
+	if o.DisableRollback {
+		return cloudformation.OnFailureDoNothing
+	}
+
+	return o.OnFailure
+}
+
 // Change is a change that is applied to the stack.
 type Change struct {
 	Action            string
 	ResourceType      string
 	LogicalResourceID string
 	ReplacementNeeded bool
+
+	// Replacement is True, False or Conditional. Conditional means that the
+	// replacement depends on the values that are known only when the change
+	// set is executed.
+	Replacement string
+
+	// Scope lists the attributes of the resource that are changed (e.g.
+	// Properties, Tags).
+	Scope []string
+
+	// Details are the changes of the individual attributes (properties) of
+	// the resource.
+	Details []ChangeDetail
+}
+
+// ChangeDetail describes the change of a single attribute (property) of the
+// resource and what causes it.
+type ChangeDetail struct {
+	// Attribute is the changed attribute of the resource, e.g. Properties,
+	// Metadata or Tags.
+	Attribute string
+
+	// Name is the name of the changed property. Empty unless Attribute is
+	// Properties.
+	Name string
+
+	// RequiresRecreation is Never, Conditionally or Always.
+	RequiresRecreation string
+
+	// Evaluation is Static if the change is known when the change set is
+	// created or Dynamic if it's determined only on execution.
+	Evaluation string
+
+	// ChangeSource is the type of the entity causing the change, e.g.
+	// DirectModification or ParameterReference.
+	ChangeSource string
+
+	// CausingEntity is the identity of the entity causing the change, e.g.
+	// the name of the referenced parameter.
+	CausingEntity string
+}
+
+// Target returns the path of the changed attribute, e.g.
+// Properties.BucketName.
+func (d ChangeDetail) Target() string {
+	if d.Name == "" {
+		return d.Attribute
+	}
+
+	return d.Attribute + "." + d.Name
+}
+
+// Cause returns a human readable description of what causes the change.
+func (d ChangeDetail) Cause() string {
+	if d.CausingEntity == "" {
+		return d.ChangeSource
+	}
+
+	return d.ChangeSource + " (" + d.CausingEntity + ")"
 }
 
 func (cs *ChangeSet) Stack() *Stack {
@@ -44,6 +135,20 @@ func (cs *ChangeSet) WithTemplateURL(url string) *ChangeSet {
 	return cs
 }
 
+// TemplateBody returns the template of the change set. The template referred
+// by the url is downloaded from s3.
+func (cs *ChangeSet) TemplateBody() (string, error) {
+	if cs.url == "" {
+		return cs.body, nil
+	}
+
+	if cs.stack.uploader == nil {
+		return "", fmt.Errorf("template %s can't be downloaded without s3 client", cs.url)
+	}
+
+	return cs.stack.uploader.Download(cs.url)
+}
+
 func (cs *ChangeSet) WithParameters(parameters map[string]string) *ChangeSet {
 	cs.parameters = parameters
 	return cs
@@ -101,6 +206,37 @@ func (cs *ChangeSet) WithUsePrevTpl(usePrevTpl bool) *ChangeSet {
 	return cs
 }
 
+// WithName sets the name of the change set. By default the name is generated.
+// The change set with the same name that is already registered in the stack
+// is replaced.
+func (cs *ChangeSet) WithName(name string) *ChangeSet {
+	cs.name = name
+	return cs
+}
+
+// WithResourcesToImport makes the change set import the existing resources
+// into the stack. The resources are identified by their logical IDs in the
+// template. Resource identifier is a set of properties identifying the
+// resource, e.g. {"BucketName": "my-bucket"} for AWS::S3::Bucket.
+func (cs *ChangeSet) WithResourcesToImport(resources map[string]map[string]string) *ChangeSet {
+	cs.resourcesToImport = resources
+	return cs
+}
+
+// WithCreationOptions sets the options applied if the change set creates the
+// stack.
+func (cs *ChangeSet) WithCreationOptions(opts CreationOptions) *ChangeSet {
+	cs.creationOpts = opts
+	return cs
+}
+
+// WithNestedStacks makes the change set include the changes of the nested
+// stacks (AWS::CloudFormation::Stack resources).
+func (cs *ChangeSet) WithNestedStacks(include bool) *ChangeSet {
+	cs.includeNestedStacks = include
+	return cs
+}
+
 func (cs *ChangeSet) WithNotificationARNs(arns []string) *ChangeSet {
 	if len(arns) > 0 {
 		cs.input.NotificationARNs = aws.StringSlice(arns)
@@ -109,10 +245,13 @@ func (cs *ChangeSet) WithNotificationARNs(arns []string) *ChangeSet {
 	return cs
 }
 
-func (cs *ChangeSet) Register() (*ChangeSetHandle, error) {
+// Register creates the change set and waits until its creation is complete.
+// If ctx is canceled while waiting, the change set is deleted.
+func (cs *ChangeSet) Register(ctx context.Context) (*ChangeSetHandle, error) {
 	chSet := &ChangeSetHandle{
 		cf:        cs.stack.cf,
 		stackName: cs.stack.Name,
+		waitOpts:  cs.stack.waitOpts,
 	}
 
 	if err := cs.setupTplLocation(); err != nil {
@@ -129,33 +268,97 @@ func (cs *ChangeSet) Register() (*ChangeSetHandle, error) {
 		return chSet, err
 	}
 
+	if chSet.IsUpdate {
+		if err = cs.stack.checkUpdatable(); err != nil {
+			return chSet, err
+		}
+	}
+
 	operation := cloudformation.ChangeSetTypeCreate
 	if chSet.IsUpdate {
 		operation = cloudformation.ChangeSetTypeUpdate
 	}
 
+	if len(cs.resourcesToImport) > 0 {
+		operation = cloudformation.ChangeSetTypeImport
+		chSet.IsImport = true
+
+		cs.input.ResourcesToImport, err = cs.awsResourcesToImport()
+		if err != nil {
+			return chSet, err
+		}
+	}
+
 	cs.input.ChangeSetType = aws.String(operation)
 	cs.input.ChangeSetName = aws.String("chst-" + strconv.FormatInt(time.Now().UnixNano(), 10))
+
+	if cs.name != "" {
+		if err = cs.deleteNamesake(); err != nil {
+			return chSet, err
+		}
+
+		cs.input.ChangeSetName = aws.String(cs.name)
+	}
+
 	cs.input.StackName = aws.String(cs.stack.Name)
 	cs.input.Parameters = awsParams
 	cs.input.Tags = cs.awsTags()
 
-	output, err := cs.stack.cf.CreateChangeSet(&cs.input)
+	if operation == cloudformation.ChangeSetTypeCreate && cs.creationOpts.WaitTimeoutInMinutes > 0 {
+		chSet.waitOpts.Timeout = time.Duration(cs.creationOpts.WaitTimeoutInMinutes) * time.Minute
+	}
 
+	if cs.includeNestedStacks {
+		cs.input.IncludeNestedStacks = aws.Bool(true)
+	}
+
+	if onFailure := cs.creationOpts.onStackFailure(); onFailure != "" && operation == cloudformation.ChangeSetTypeCreate {
+		cs.input.OnStackFailure = aws.String(onFailure)
+	}
+
+	output, err := cs.stack.cf.CreateChangeSetWithContext(ctx, &cs.input)
 	if err != nil {
 		return chSet, err
 	}
 
 	chSet.ID = aws.StringValue(output.Id)
 
-	err = cs.wait(output.Id)
+	err = cs.wait(ctx, output.Id)
 	if err != nil {
+		if ctx.Err() != nil {
+			if derr := chSet.Delete(); derr != nil {
+				return chSet, fmt.Errorf("%v. Failed to delete change set %s: %v", err, chSet.ID, derr)
+			}
+		}
+
 		return chSet, err
 	}
 
+	chSet.input = &cs.input
+
 	return chSet, chSet.loadChanges()
 }
 
+// deleteNamesake deletes the previously registered change set having the same
+// name as the change set.
+func (cs *ChangeSet) deleteNamesake() error {
+	exists, err := cs.stack.Exists()
+	if err != nil || !exists {
+		return err
+	}
+
+	_, err = cs.stack.cf.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		ChangeSetName: aws.String(cs.name),
+		StackName:     aws.String(cs.stack.Name),
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudformation.ErrCodeChangeSetNotFoundException {
+		return nil
+	}
+
+	return err
+}
+
 func (cs *ChangeSet) setupTplLocation() error {
 	if cs.url != "" {
 		cs.input.TemplateURL = aws.String(cs.url)
@@ -177,15 +380,13 @@ func (cs *ChangeSet) setupTplLocation() error {
 	return nil
 }
 
-func (cs *ChangeSet) wait(id *string) error {
+func (cs *ChangeSet) wait(ctx context.Context, id *string) error {
 	err := cs.stack.cf.WaitUntilChangeSetCreateCompleteWithContext(
-		aws.BackgroundContext(),
+		ctx,
 		&cloudformation.DescribeChangeSetInput{
 			ChangeSetName: id,
 		},
-		func(w *request.Waiter) {
-			w.Delay = request.ConstantWaiterDelay(1 * time.Second)
-		},
+		cs.stack.waitOpts.changeSetWaiter(),
 	)
 
 	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == request.WaiterResourceNotReadyErrorCode {
@@ -205,6 +406,11 @@ func (cs *ChangeSet) wait(id *string) error {
 			return ErrNoChange
 		}
 
+		if isTimeout(err) {
+			return fmt.Errorf("[%s] change set is not created within %s. Status: %s, StatusReason: %s",
+				*setInfo.ChangeSetId, cs.stack.waitOpts.changeSetTimeout(), *setInfo.Status, *setInfo.StatusReason)
+		}
+
 		return fmt.Errorf("[%s] %s. Status: %s, StatusReason: %s", *setInfo.ChangeSetId, err.Error(), *setInfo.Status, *setInfo.StatusReason)
 	}
 
@@ -237,6 +443,48 @@ func (cs *ChangeSet) awsParameters() ([]*cloudformation.Parameter, error) {
 	return pb.collect()
 }
 
+func (cs *ChangeSet) awsResourcesToImport() ([]*cloudformation.ResourceToImport, error) {
+	// the types of the imported resources are looked up in the template
+	body, err := cs.TemplateBody()
+	if err != nil {
+		return nil, err
+	}
+
+	if body == "" {
+		return nil, errors.New("template is required to import resources")
+	}
+
+	resources, err := templateResources(body)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(cs.resourcesToImport))
+	for id := range cs.resourcesToImport {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	awsResources := make([]*cloudformation.ResourceToImport, 0, len(ids))
+
+	for _, id := range ids {
+		resType := resourceType(resources[id])
+
+		if resType == "" {
+			return nil, fmt.Errorf("resource %s to import is not found in the template", id)
+		}
+
+		awsResources = append(awsResources, &cloudformation.ResourceToImport{
+			LogicalResourceId:  aws.String(id),
+			ResourceType:       aws.String(resType),
+			ResourceIdentifier: aws.StringMap(cs.resourcesToImport[id]),
+		})
+	}
+
+	return awsResources, nil
+}
+
 func (cs *ChangeSet) awsTags() []*cloudformation.Tag {
 	awsTags := make([]*cloudformation.Tag, 0, len(cs.tags))
 
@@ -265,11 +513,35 @@ type ChangeSetHandle struct {
 	ID        string
 	Changes   []Change
 	IsUpdate  bool
+	IsImport  bool
 	stackName string
 	cf        cloudformationiface.CloudFormationAPI
+	waitOpts  WaitOpts
+
+	// Parameters are the parameters of the change set as they are resolved
+	// by cloudformation.
+	Parameters []KeyVal
+	Tags       []KeyVal
+
+	// StackID is the ARN of the stack of the change set.
+	StackID string
+
+	// ExecutionStatus tells whether the change set can be executed.
+	ExecutionStatus string
+
+	// Executed tells whether the execution of the change set is started by
+	// Exec. The change sets that are not executed are left for cleanup.
+	Executed bool
+
+	// input is the input the change set is registered with. It's nil if the
+	// change set is loaded.
+	input *cloudformation.CreateChangeSetInput
 }
 
-func (csh ChangeSetHandle) Exec() error {
+// Exec executes the change set and waits until the stack operation is
+// complete. If ctx is canceled, Exec stops waiting but the stack operation
+// keeps going.
+func (csh *ChangeSetHandle) Exec(ctx context.Context) error {
 	_, err := csh.cf.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
 		ChangeSetName: aws.String(csh.ID),
 	})
@@ -277,23 +549,103 @@ func (csh ChangeSetHandle) Exec() error {
 		return err
 	}
 
+	csh.Executed = true
+
+	return csh.Wait(ctx)
+}
+
+// ExecWithPolicy executes the update change set with the stack policy
+// overridden for the time of the update. Change sets don't support
+// StackPolicyDuringUpdateBody, so the change set is deleted and the stack is
+// updated directly with the input the change set is registered with. Only the
+// change sets registered by ChangeSet.Register can be executed this way.
+func (csh *ChangeSetHandle) ExecWithPolicy(ctx context.Context, policy string) error {
+	if !csh.IsUpdate || csh.IsImport || csh.input == nil {
+		return errors.New("stack policy can be overridden only for the registered update change set")
+	}
+
+	if err := csh.Delete(); err != nil {
+		return err
+	}
+
+	// the change set is gone, there is nothing to clean up anymore
+	csh.Executed = true
+
+	in := csh.input
+
+	_, err := csh.cf.UpdateStack(&cloudformation.UpdateStackInput{
+		StackName:                   in.StackName,
+		TemplateBody:                in.TemplateBody,
+		TemplateURL:                 in.TemplateURL,
+		UsePreviousTemplate:         in.UsePreviousTemplate,
+		Parameters:                  in.Parameters,
+		Tags:                        in.Tags,
+		Capabilities:                in.Capabilities,
+		ResourceTypes:               in.ResourceTypes,
+		RoleARN:                     in.RoleARN,
+		RollbackConfiguration:       in.RollbackConfiguration,
+		NotificationARNs:            in.NotificationARNs,
+		StackPolicyDuringUpdateBody: aws.String(policy),
+	})
+	if err != nil {
+		return err
+	}
+
+	return csh.Wait(ctx)
+}
+
+// Wait waits until the stack operation caused by the execution of the change
+// set is complete.
+func (csh ChangeSetHandle) Wait(ctx context.Context) error {
 	stackInput := cloudformation.DescribeStacksInput{
 		StackName: aws.String(csh.stackName),
 	}
 
-	ctx := aws.BackgroundContext()
+	var err error
 
-	if csh.IsUpdate {
-		return csh.cf.WaitUntilStackUpdateCompleteWithContext(ctx, &stackInput, func(w *request.Waiter) {
-			w.MaxAttempts = 900
-			w.Delay = request.ConstantWaiterDelay(2 * time.Second)
-		})
+	switch {
+	case csh.IsImport:
+		err = csh.cf.WaitUntilStackImportCompleteWithContext(ctx, &stackInput, csh.waitOpts.stackWaiter())
+	case csh.IsUpdate:
+		err = csh.cf.WaitUntilStackUpdateCompleteWithContext(ctx, &stackInput, csh.waitOpts.stackWaiter())
+	default:
+		err = csh.cf.WaitUntilStackCreateCompleteWithContext(ctx, &stackInput, csh.waitOpts.stackWaiter())
 	}
 
-	return csh.cf.WaitUntilStackCreateCompleteWithContext(ctx, &stackInput, func(w *request.Waiter) {
-		w.MaxAttempts = 900
-		w.Delay = request.ConstantWaiterDelay(2 * time.Second)
+	return timeoutError(err, csh.cf, csh.stackName, csh.waitOpts.stackTimeout())
+}
+
+// ConsoleURL returns the link to the change set in the AWS console.
+func (csh ChangeSetHandle) ConsoleURL() string {
+	// arn:partition:cloudformation:region:account:changeSet/name/id
+	parts := strings.SplitN(csh.ID, ":", 5)
+	if len(parts) < 5 {
+		return csh.ID
+	}
+
+	domain := "console.aws.amazon.com"
+
+	switch parts[1] {
+	case "aws-cn":
+		domain = "console.amazonaws.cn"
+	case "aws-us-gov":
+		domain = "console.amazonaws-us-gov.com"
+	}
+
+	region := parts[3]
+
+	return fmt.Sprintf("https://%s.%s/cloudformation/home?region=%s#/stacks/changesets/changes?stackId=%s&changeSetId=%s",
+		region, domain, region, url.QueryEscape(csh.StackID), url.QueryEscape(csh.ID))
+}
+
+// Delete deletes the change set. Only the change sets that are not executed
+// can be deleted.
+func (csh ChangeSetHandle) Delete() error {
+	_, err := csh.cf.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		ChangeSetName: aws.String(csh.ID),
 	})
+
+	return err
 }
 
 func (csh *ChangeSetHandle) loadChanges() error {
@@ -301,7 +653,7 @@ func (csh *ChangeSetHandle) loadChanges() error {
 	return csh.changes(&csh.Changes, nil)
 }
 
-func (csh ChangeSetHandle) changes(store *[]Change, nextToken *string) error {
+func (csh *ChangeSetHandle) changes(store *[]Change, nextToken *string) error {
 	setInfo, err := csh.cf.DescribeChangeSet(&cloudformation.DescribeChangeSetInput{
 		ChangeSetName: aws.String(csh.ID),
 		NextToken:     nextToken,
@@ -319,18 +671,56 @@ func (csh ChangeSetHandle) changes(store *[]Change, nextToken *string) error {
 		return errors.New(*setInfo.StatusReason)
 	}
 
+	if nextToken == nil {
+		csh.ExecutionStatus = aws.StringValue(setInfo.ExecutionStatus)
+		csh.StackID = aws.StringValue(setInfo.StackId)
+		csh.Parameters = make([]KeyVal, 0, len(setInfo.Parameters))
+
+		for _, p := range setInfo.Parameters {
+			csh.Parameters = append(csh.Parameters, KeyVal{
+				Key: aws.StringValue(p.ParameterKey),
+				Val: aws.StringValue(p.ParameterValue),
+			})
+		}
+
+		csh.Tags = make([]KeyVal, 0, len(setInfo.Tags))
+
+		for _, t := range setInfo.Tags {
+			csh.Tags = append(csh.Tags, KeyVal{Key: aws.StringValue(t.Key), Val: aws.StringValue(t.Value)})
+		}
+	}
+
 	for _, c := range setInfo.Changes {
 		awsChange := c.ResourceChange
 		ch := Change{
 			Action:            aws.StringValue(awsChange.Action),
 			ResourceType:      aws.StringValue(awsChange.ResourceType),
 			LogicalResourceID: aws.StringValue(awsChange.LogicalResourceId),
+			Replacement:       aws.StringValue(awsChange.Replacement),
+			Scope:             aws.StringValueSlice(awsChange.Scope),
+			Details:           make([]ChangeDetail, 0, len(awsChange.Details)),
 		}
 
-		if aws.StringValue(awsChange.Replacement) == "True" {
+		if ch.Replacement == cloudformation.ReplacementTrue {
 			ch.ReplacementNeeded = true
 		}
 
+		for _, d := range awsChange.Details {
+			detail := ChangeDetail{
+				Evaluation:    aws.StringValue(d.Evaluation),
+				ChangeSource:  aws.StringValue(d.ChangeSource),
+				CausingEntity: aws.StringValue(d.CausingEntity),
+			}
+
+			if d.Target != nil {
+				detail.Attribute = aws.StringValue(d.Target.Attribute)
+				detail.Name = aws.StringValue(d.Target.Name)
+				detail.RequiresRecreation = aws.StringValue(d.Target.RequiresRecreation)
+			}
+
+			ch.Details = append(ch.Details, detail)
+		}
+
 		*store = append(*store, ch)
 	}
 
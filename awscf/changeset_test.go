@@ -0,0 +1,56 @@
+package awscf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreationOptionsAreAppliedToNewStack(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusReviewInProgress}
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).
+		ChangeSet("body").
+		WithCreationOptions(CreationOptions{WaitTimeoutInMinutes: 15, DisableRollback: true}).
+		WithNestedStacks(true).
+		Register(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 15*time.Minute, chSet.waitOpts.Timeout)
+	assert.Equal(t, aws.Bool(true), cf.createChangeSetInput.IncludeNestedStacks)
+	assert.Equal(t, aws.String(cloudformation.OnStackFailureDoNothing), cf.createChangeSetInput.OnStackFailure)
+}
+
+func TestCreationOptionsAreIgnoredOnUpdate(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusCreateComplete}
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).
+		ChangeSet("body").
+		WithCreationOptions(CreationOptions{WaitTimeoutInMinutes: 15, OnFailure: cloudformation.OnFailureDelete}).
+		Register(context.Background())
+	require.NoError(t, err)
+
+	assert.Zero(t, chSet.waitOpts.Timeout)
+	assert.Nil(t, cf.createChangeSetInput.IncludeNestedStacks)
+	assert.Nil(t, cf.createChangeSetInput.OnStackFailure)
+}
+
+func TestChangeSetConsoleURL(t *testing.T) {
+	chSet := ChangeSetHandle{
+		ID:      "arn:aws:cloudformation:eu-west-1:123456789012:changeSet/stas-pending/0a1b",
+		StackID: "arn:aws:cloudformation:eu-west-1:123456789012:stack/app/9c8d",
+	}
+
+	assert.Equal(t, "https://eu-west-1.console.aws.amazon.com/cloudformation/home?region=eu-west-1"+
+		"#/stacks/changesets/changes"+
+		"?stackId=arn%3Aaws%3Acloudformation%3Aeu-west-1%3A123456789012%3Astack%2Fapp%2F9c8d"+
+		"&changeSetId=arn%3Aaws%3Acloudformation%3Aeu-west-1%3A123456789012%3AchangeSet%2Fstas-pending%2F0a1b",
+		chSet.ConsoleURL())
+
+	assert.Equal(t, "chst-123", ChangeSetHandle{ID: "chst-123"}.ConsoleURL())
+}
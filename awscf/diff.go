@@ -1,18 +1,33 @@
 package awscf
 
 import (
+	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/molecule-man/stack-assembly/cli"
 	"github.com/pmezard/go-difflib/difflib"
 )
 
 const defaultDiffName = "/dev/null"
 
+// Modes of the template body diff.
+const (
+	// DiffModeText compares the templates line by line.
+	DiffModeText = "text"
+
+	// DiffModeSemantic compares the parsed templates and reports the changed
+	// values by their paths.
+	DiffModeSemantic = "semantic"
+)
+
 type ChSetDiff struct {
 	Color cli.Color
+
+	// Mode is either DiffModeText (default) or DiffModeSemantic.
+	Mode string
 }
 
 func (d ChSetDiff) Diff(chSet *ChangeSet) (string, error) {
@@ -36,6 +51,19 @@ func (d ChSetDiff) Diff(chSet *ChangeSet) (string, error) {
 		diffs = append(diffs, d.colorizeDiff(tagsDiff))
 	}
 
+	if d.Mode == DiffModeSemantic {
+		bodyDiff, err := d.semanticDiffBody(chSet)
+		if err != nil {
+			return "", err
+		}
+
+		if len(bodyDiff) > 0 {
+			diffs = append(diffs, bodyDiff)
+		}
+
+		return strings.Join(diffs, "\n"), nil
+	}
+
 	bodyDiff, err := diffBody(chSet)
 	if err != nil {
 		return "", err
@@ -48,22 +76,50 @@ func (d ChSetDiff) Diff(chSet *ChangeSet) (string, error) {
 	return strings.Join(diffs, "\n"), nil
 }
 
-func diffBody(chSet *ChangeSet) (string, error) {
-	oldBody := ""
-	oldName := defaultDiffName
+// Changes describes the property level changes of the resources: which
+// properties are changed, whether the change requires recreation of the
+// resource and what causes the change. The resources changed without
+// details (e.g. added or removed ones) are omitted.
+func (d ChSetDiff) Changes(changes []Change) string {
+	lines := []string{}
 
-	deployed, err := chSet.Stack().AlreadyDeployed()
-	if err != nil {
-		return "", err
-	}
+	for _, c := range changes {
+		if len(c.Details) == 0 {
+			continue
+		}
 
-	if deployed {
-		oldBody, err = chSet.Stack().Body()
-		if err != nil {
-			return "", err
+		header := fmt.Sprintf("%s %s (%s)", c.Action, c.LogicalResourceID, c.ResourceType)
+		if c.Replacement != "" {
+			header += ". Replacement: " + c.Replacement
 		}
 
-		oldName = "old/" + chSet.Stack().Name
+		lines = append(lines, d.Color.Yellow(header))
+
+		for _, detail := range c.Details {
+			recreation := detail.RequiresRecreation
+			switch recreation {
+			case cloudformation.RequiresRecreationAlways:
+				recreation = d.Color.Red(recreation)
+			case cloudformation.RequiresRecreationConditionally:
+				recreation = d.Color.Yellow(recreation)
+			}
+
+			line := fmt.Sprintf("  %s: recreation %s, caused by %s", detail.Target(), recreation, detail.Cause())
+			if detail.Evaluation == cloudformation.EvaluationTypeDynamic {
+				line += " (evaluated on execution)"
+			}
+
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func diffBody(chSet *ChangeSet) (string, error) {
+	oldBody, oldName, err := deployedBody(chSet)
+	if err != nil {
+		return "", err
 	}
 
 	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
@@ -77,6 +133,59 @@ func diffBody(chSet *ChangeSet) (string, error) {
 	})
 }
 
+func (d ChSetDiff) semanticDiffBody(chSet *ChangeSet) (string, error) {
+	oldBody, oldName, err := deployedBody(chSet)
+	if err != nil {
+		return "", err
+	}
+
+	changes, err := DiffTemplates(oldBody, chSet.body)
+	if err != nil || len(changes) == 0 {
+		return "", err
+	}
+
+	lines := []string{
+		d.Color.Yellow("--- " + oldName),
+		d.Color.Yellow("+++ new/" + chSet.Stack().Name),
+	}
+	section := ""
+
+	for _, c := range changes {
+		if c.Section != section {
+			section = c.Section
+			lines = append(lines, d.Color.Cyan(section+":"))
+		}
+
+		switch c.Kind {
+		case TemplateValueAdded:
+			lines = append(lines, d.Color.Green("  + "+c.Path+": "+FormatTemplateValue(c.New)))
+		case TemplateValueRemoved:
+			lines = append(lines, d.Color.Red("  - "+c.Path+": "+FormatTemplateValue(c.Old)))
+		default:
+			lines = append(lines, d.Color.Yellow(fmt.Sprintf("  ~ %s: %s -> %s",
+				c.Path, FormatTemplateValue(c.Old), FormatTemplateValue(c.New))))
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// deployedBody returns the body of the deployed template and its name in the
+// diff. The body is empty if the stack is not deployed yet.
+func deployedBody(chSet *ChangeSet) (string, string, error) {
+	deployed, err := chSet.Stack().AlreadyDeployed()
+	if err != nil || !deployed {
+		return "", defaultDiffName, err
+	}
+
+	body, err := chSet.Stack().Body()
+	if err != nil {
+		return "", defaultDiffName, err
+	}
+
+	return body, "old/" + chSet.Stack().Name, nil
+}
+
 func diffParameters(chSet *ChangeSet) (string, error) {
 	awsParams, err := chSet.awsParameters()
 	if err != nil {
@@ -5,13 +5,15 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/molecule-man/stack-assembly/cli"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 func TestDiffWhenStackExists(t *testing.T) {
-	d := ChSetDiff{cli.Color{Disabled: true}}
+	d := ChSetDiff{Color: cli.Color{Disabled: true}}
 	oldTplBody := `
 parameters:
   param1: old_val1
@@ -41,7 +43,7 @@ parameters:
 }
 
 func TestDiffWhenStackDoesntExist(t *testing.T) {
-	d := ChSetDiff{cli.Color{Disabled: true}}
+	d := ChSetDiff{Color: cli.Color{Disabled: true}}
 	newTplBody := `
 parameters:
   param1: val1
@@ -65,3 +67,133 @@ parameters:
 `
 	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(diff))
 }
+
+func TestChangesShowPropertyLevelDetails(t *testing.T) {
+	d := ChSetDiff{Color: cli.Color{Disabled: true}}
+	changes := []Change{{
+		Action:            "Add",
+		ResourceType:      "AWS::SQS::Queue",
+		LogicalResourceID: "Queue",
+	}, {
+		Action:            "Modify",
+		ResourceType:      "AWS::S3::Bucket",
+		LogicalResourceID: "Bucket",
+		Replacement:       "Conditional",
+		Details: []ChangeDetail{{
+			Attribute:          "Properties",
+			Name:               "BucketName",
+			RequiresRecreation: "Always",
+			Evaluation:         "Static",
+			ChangeSource:       "ParameterReference",
+			CausingEntity:      "Env",
+		}, {
+			Attribute:          "Tags",
+			RequiresRecreation: "Never",
+			Evaluation:         "Dynamic",
+			ChangeSource:       "DirectModification",
+		}},
+	}}
+
+	expected := `
+Modify Bucket (AWS::S3::Bucket). Replacement: Conditional
+  Properties.BucketName: recreation Always, caused by ParameterReference (Env)
+  Tags: recreation Never, caused by DirectModification (evaluated on execution)
+`
+	assert.Equal(t, strings.TrimSpace(expected), d.Changes(changes))
+}
+
+func TestSemanticDiffGroupsChangesBySection(t *testing.T) {
+	d := ChSetDiff{Color: cli.Color{Disabled: true}, Mode: DiffModeSemantic}
+
+	cf := &cfMock{}
+	cf.body = `{"Resources": {"Bucket": {"Type": "AWS::S3::Bucket",
+		"Properties": {"VersioningConfiguration": {"Status": "Suspended"}}}}}`
+	chSet := NewStack("teststack", cf, nil).ChangeSet(`
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      VersioningConfiguration:
+        Status: Enabled
+Outputs:
+  Name:
+    Value: !Ref Bucket
+`)
+
+	diff, err := d.Diff(chSet)
+	require.NoError(t, err)
+
+	expected := `
+--- old/teststack
++++ new/teststack
+Outputs:
+  + Outputs: {"Name":{"Value":{"Ref":"Bucket"}}}
+Resources:
+  ~ Resources.Bucket.Properties.VersioningConfiguration.Status: Suspended -> Enabled
+`
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(diff))
+}
+
+func TestSemanticDiffWhenStackDoesntExist(t *testing.T) {
+	d := ChSetDiff{Color: cli.Color{Disabled: true}, Mode: DiffModeSemantic}
+
+	cf := &cfMock{}
+	cf.describeErr = errors.New("stack does not exist")
+	chSet := NewStack("teststack", cf, nil).ChangeSet(`
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+Outputs:
+  Name:
+    Value: !Ref Bucket
+`)
+
+	diff, err := d.Diff(chSet)
+	require.NoError(t, err)
+
+	expected := `
+--- /dev/null
++++ new/teststack
+Outputs:
+  + Outputs: {"Name":{"Value":{"Ref":"Bucket"}}}
+Resources:
+  + Resources: {"Bucket":{"Type":"AWS::S3::Bucket"}}
+`
+	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(diff))
+}
+
+func TestStackDiff(t *testing.T) {
+	cf := &cfMock{}
+	cf.templateParameters = []*cloudformation.TemplateParameter{{ParameterKey: aws.String("Env")}}
+	cf.body = `
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+  Topic:
+    Type: AWS::SNS::Topic`
+
+	chSet := NewStack("teststack", cf, nil).ChangeSet(`
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      BucketName: !Sub "${Env}-bucket"
+  Queue:
+    Type: AWS::SQS::Queue`).
+		WithParameters(map[string]string{"Env": "dev"}).
+		WithTags(map[string]string{"team": "core"})
+
+	sd, err := ChSetDiff{}.StackDiff(chSet)
+	require.NoError(t, err)
+
+	assert.True(t, sd.HasChanges())
+	assert.Equal(t, "teststack", sd.Stack)
+	assert.Equal(t, []KeyChange{{Key: "Env", Kind: TemplateValueAdded, New: "dev"}}, sd.Parameters)
+	assert.Equal(t, []KeyChange{{Key: "team", Kind: TemplateValueAdded, New: "core"}}, sd.Tags)
+	assert.Equal(t, []ResourceDiff{
+		{LogicalResourceID: "Bucket", ResourceType: "AWS::S3::Bucket", Kind: TemplateValueModified},
+		{LogicalResourceID: "Queue", ResourceType: "AWS::SQS::Queue", Kind: TemplateValueAdded},
+		{LogicalResourceID: "Topic", ResourceType: "AWS::SNS::Topic", Kind: TemplateValueRemoved},
+	}, sd.Resources)
+	assert.Contains(t, sd.Body, "+++ new/teststack")
+}
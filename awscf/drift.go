@@ -0,0 +1,134 @@
+package awscf
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// Drift is the result of the drift detection of the stack.
+type Drift struct {
+	StackName string
+
+	// Status is DRIFTED, IN_SYNC or NOT_CHECKED.
+	Status string
+
+	Resources []ResourceDrift
+}
+
+// Drifted tells whether the stack differs from its template.
+func (d Drift) Drifted() bool {
+	return d.Status == cloudformation.StackDriftStatusDrifted
+}
+
+// ResourceDrift is the drift status of a single resource of the stack.
+type ResourceDrift struct {
+	LogicalResourceID  string
+	PhysicalResourceID string
+	ResourceType       string
+
+	// Status is IN_SYNC, MODIFIED, DELETED or NOT_CHECKED.
+	Status string
+
+	PropertyDifferences []PropertyDifference
+}
+
+// PropertyDifference is the difference between the expected and the actual
+// value of a resource property.
+type PropertyDifference struct {
+	PropertyPath string
+
+	// DifferenceType is ADD, REMOVE or NOT_EQUAL.
+	DifferenceType string
+
+	ExpectedValue string
+	ActualValue   string
+}
+
+// DetectDrift detects the drift of the stack and waits until the detection
+// is complete.
+func (s *Stack) DetectDrift(ctx context.Context) (Drift, error) {
+	drift := Drift{StackName: s.Name}
+
+	out, err := s.cf.DetectStackDrift(&cloudformation.DetectStackDriftInput{
+		StackName: aws.String(s.Name),
+	})
+	if err != nil {
+		return drift, err
+	}
+
+	deadline := time.Now().Add(s.waitOpts.stackTimeout())
+
+	for {
+		status, err := s.cf.DescribeStackDriftDetectionStatus(&cloudformation.DescribeStackDriftDetectionStatusInput{
+			StackDriftDetectionId: out.StackDriftDetectionId,
+		})
+		if err != nil {
+			return drift, err
+		}
+
+		switch aws.StringValue(status.DetectionStatus) {
+		case cloudformation.StackDriftDetectionStatusDetectionFailed:
+			return drift, fmt.Errorf("drift detection of stack %s failed: %s",
+				s.Name, aws.StringValue(status.DetectionStatusReason))
+		case cloudformation.StackDriftDetectionStatusDetectionComplete:
+			drift.Status = aws.StringValue(status.StackDriftStatus)
+			drift.Resources, err = s.resourceDrifts()
+
+			return drift, err
+		}
+
+		if time.Now().After(deadline) {
+			return drift, fmt.Errorf("drift detection of stack %s is not complete within %s",
+				s.Name, s.waitOpts.stackTimeout())
+		}
+
+		select {
+		case <-ctx.Done():
+			return drift, ctx.Err()
+		case <-time.After(s.waitOpts.pollInterval()):
+		}
+	}
+}
+
+func (s *Stack) resourceDrifts() ([]ResourceDrift, error) {
+	drifts := []ResourceDrift{}
+	input := cloudformation.DescribeStackResourceDriftsInput{StackName: aws.String(s.Name)}
+
+	for {
+		out, err := s.cf.DescribeStackResourceDrifts(&input)
+		if err != nil {
+			return drifts, err
+		}
+
+		for _, d := range out.StackResourceDrifts {
+			rd := ResourceDrift{
+				LogicalResourceID:   aws.StringValue(d.LogicalResourceId),
+				PhysicalResourceID:  aws.StringValue(d.PhysicalResourceId),
+				ResourceType:        aws.StringValue(d.ResourceType),
+				Status:              aws.StringValue(d.StackResourceDriftStatus),
+				PropertyDifferences: make([]PropertyDifference, 0, len(d.PropertyDifferences)),
+			}
+
+			for _, pd := range d.PropertyDifferences {
+				rd.PropertyDifferences = append(rd.PropertyDifferences, PropertyDifference{
+					PropertyPath:   aws.StringValue(pd.PropertyPath),
+					DifferenceType: aws.StringValue(pd.DifferenceType),
+					ExpectedValue:  aws.StringValue(pd.ExpectedValue),
+					ActualValue:    aws.StringValue(pd.ActualValue),
+				})
+			}
+
+			drifts = append(drifts, rd)
+		}
+
+		if aws.StringValue(out.NextToken) == "" {
+			return drifts, nil
+		}
+
+		input.NextToken = out.NextToken
+	}
+}
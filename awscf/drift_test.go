@@ -0,0 +1,114 @@
+package awscf
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type driftCfMock struct {
+	cfMock
+	statuses []string
+	drifts   [][]*cloudformation.StackResourceDrift
+}
+
+func (cf *driftCfMock) DetectStackDrift(*cloudformation.DetectStackDriftInput) (*cloudformation.DetectStackDriftOutput, error) {
+	return &cloudformation.DetectStackDriftOutput{StackDriftDetectionId: aws.String("detection-id")}, nil
+}
+
+func (cf *driftCfMock) DescribeStackDriftDetectionStatus(
+	*cloudformation.DescribeStackDriftDetectionStatusInput,
+) (*cloudformation.DescribeStackDriftDetectionStatusOutput, error) {
+	status := cf.statuses[0]
+	cf.statuses = cf.statuses[1:]
+
+	return &cloudformation.DescribeStackDriftDetectionStatusOutput{
+		DetectionStatus:       aws.String(status),
+		DetectionStatusReason: aws.String("access denied"),
+		StackDriftStatus:      aws.String(cloudformation.StackDriftStatusDrifted),
+	}, nil
+}
+
+func (cf *driftCfMock) DescribeStackResourceDrifts(
+	input *cloudformation.DescribeStackResourceDriftsInput,
+) (*cloudformation.DescribeStackResourceDriftsOutput, error) {
+	page := 0
+	if input.NextToken != nil {
+		page = 1
+	}
+
+	out := &cloudformation.DescribeStackResourceDriftsOutput{StackResourceDrifts: cf.drifts[page]}
+	if page+1 < len(cf.drifts) {
+		out.NextToken = aws.String("next")
+	}
+
+	return out, nil
+}
+
+func TestDetectDrift(t *testing.T) {
+	cf := &driftCfMock{
+		statuses: []string{
+			cloudformation.StackDriftDetectionStatusDetectionInProgress,
+			cloudformation.StackDriftDetectionStatusDetectionComplete,
+		},
+		drifts: [][]*cloudformation.StackResourceDrift{{{
+			LogicalResourceId:        aws.String("Bucket"),
+			PhysicalResourceId:       aws.String("my-bucket"),
+			ResourceType:             aws.String("AWS::S3::Bucket"),
+			StackResourceDriftStatus: aws.String(cloudformation.StackResourceDriftStatusModified),
+			PropertyDifferences: []*cloudformation.PropertyDifference{{
+				PropertyPath:   aws.String("/VersioningConfiguration/Status"),
+				DifferenceType: aws.String(cloudformation.DifferenceTypeNotEqual),
+				ExpectedValue:  aws.String("Enabled"),
+				ActualValue:    aws.String("Suspended"),
+			}},
+		}}, {{
+			LogicalResourceId:        aws.String("Queue"),
+			PhysicalResourceId:       aws.String("my-queue"),
+			ResourceType:             aws.String("AWS::SQS::Queue"),
+			StackResourceDriftStatus: aws.String(cloudformation.StackResourceDriftStatusInSync),
+		}}},
+	}
+
+	drift, err := NewStack("mystack", cf, nil).
+		WithWaitOpts(WaitOpts{PollInterval: time.Millisecond}).
+		DetectDrift(context.Background())
+	require.NoError(t, err)
+
+	expected := Drift{
+		StackName: "mystack",
+		Status:    cloudformation.StackDriftStatusDrifted,
+		Resources: []ResourceDrift{{
+			LogicalResourceID:  "Bucket",
+			PhysicalResourceID: "my-bucket",
+			ResourceType:       "AWS::S3::Bucket",
+			Status:             cloudformation.StackResourceDriftStatusModified,
+			PropertyDifferences: []PropertyDifference{{
+				PropertyPath:   "/VersioningConfiguration/Status",
+				DifferenceType: cloudformation.DifferenceTypeNotEqual,
+				ExpectedValue:  "Enabled",
+				ActualValue:    "Suspended",
+			}},
+		}, {
+			LogicalResourceID:   "Queue",
+			PhysicalResourceID:  "my-queue",
+			ResourceType:        "AWS::SQS::Queue",
+			Status:              cloudformation.StackResourceDriftStatusInSync,
+			PropertyDifferences: []PropertyDifference{},
+		}},
+	}
+	assert.Equal(t, expected, drift)
+	assert.True(t, drift.Drifted())
+}
+
+func TestFailedDriftDetection(t *testing.T) {
+	cf := &driftCfMock{statuses: []string{cloudformation.StackDriftDetectionStatusDetectionFailed}}
+
+	_, err := NewStack("mystack", cf, nil).DetectDrift(context.Background())
+	assert.EqualError(t, err, "drift detection of stack mystack failed: access denied")
+}
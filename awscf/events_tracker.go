@@ -1,12 +1,40 @@
 package awscf
 
+import (
+	"sort"
+	"time"
+)
+
 type EventsTrack struct {
 	stack *Stack
 
 	seenEvents      map[string]bool
 	trackingStarted bool
+
+	// collected are the fresh events returned so far. Newer events appear
+	// at the beginning of the slice
+	collected StackEvents
+
+	// path is the path of logical IDs leading from the tracked root stack to
+	// the nested stack. It's empty for the root stack
+	path string
+	// since is the moment the events of a nested stack become fresh
+	since time.Time
+
+	nested        map[string]*EventsTrack
+	pendingNested map[string]time.Time
 }
 
+// Collected returns all the fresh events returned since the tracking started.
+// Newer events appear at the beginning of the slice. The events of the nested
+// stacks aren't included.
+func (et *EventsTrack) Collected() StackEvents {
+	return et.collected
+}
+
+// FreshEvents returns the events that appeared since the last call. The
+// events of the nested stacks are merged into the result. Their NestedStack
+// field contains the logical ID of the nested stack.
 func (et *EventsTrack) FreshEvents() (StackEvents, error) {
 	emptyEvents := StackEvents{}
 
@@ -28,18 +56,124 @@ func (et *EventsTrack) FreshEvents() (StackEvents, error) {
 		return emptyEvents, nil
 	}
 
-	if len(events) == 0 {
-		return emptyEvents, nil
+	if et.seenEvents == nil {
+		et.seenEvents = make(map[string]bool, len(events))
 	}
 
 	freshEvents := make(StackEvents, 0, len(events))
 
 	for _, e := range events {
-		if _, ok := et.seenEvents[e.ID]; !ok {
-			freshEvents = append(freshEvents, e)
-			et.seenEvents[e.ID] = true
+		if _, ok := et.seenEvents[e.ID]; ok || e.Timestamp.Before(et.since) {
+			continue
+		}
+
+		et.seenEvents[e.ID] = true
+		e.NestedStack = et.path
+		freshEvents = append(freshEvents, e)
+	}
+
+	et.collected = append(append(StackEvents{}, freshEvents...), et.collected...)
+
+	nestedEvents, err := et.nestedFreshEvents(freshEvents)
+	if len(nestedEvents) == 0 {
+		return freshEvents, err
+	}
+
+	freshEvents = append(freshEvents, nestedEvents...)
+
+	sort.SliceStable(freshEvents, func(i, j int) bool {
+		return freshEvents[i].Timestamp.After(freshEvents[j].Timestamp)
+	})
+
+	return freshEvents, err
+}
+
+func (et *EventsTrack) nestedFreshEvents(freshEvents StackEvents) (StackEvents, error) {
+	for _, e := range freshEvents {
+		if e.ResourceType != nestedStackType || et.isOwnEvent(e) {
+			continue
+		}
+
+		if _, ok := et.nested[e.LogicalResourceID]; ok {
+			continue
+		}
+
+		if et.pendingNested == nil {
+			et.pendingNested = make(map[string]time.Time)
 		}
+
+		// freshEvents are ordered from newest to oldest, so the oldest
+		// timestamp wins
+		et.pendingNested[e.LogicalResourceID] = e.Timestamp
+	}
+
+	var err error
+
+	if len(et.pendingNested) > 0 {
+		err = et.discoverNested()
 	}
 
-	return freshEvents, nil
+	nestedEvents := StackEvents{}
+
+	for _, id := range et.nestedIDs() {
+		events, nestedErr := et.nested[id].FreshEvents()
+		if nestedErr != nil {
+			err = nestedErr
+		}
+
+		nestedEvents = append(nestedEvents, events...)
+	}
+
+	return nestedEvents, err
+}
+
+// discoverNested finds the physical IDs of the pending nested stacks.
+func (et *EventsTrack) discoverNested() error {
+	resources, err := et.stack.Resources()
+	if err != nil {
+		return err
+	}
+
+	if et.nested == nil {
+		et.nested = make(map[string]*EventsTrack)
+	}
+
+	for _, r := range resources {
+		since, ok := et.pendingNested[r.LogicalID]
+		if !ok || r.Type != nestedStackType || r.PhysicalID == "" {
+			continue
+		}
+
+		path := r.LogicalID
+		if et.path != "" {
+			path = et.path + "/" + r.LogicalID
+		}
+
+		et.nested[r.LogicalID] = &EventsTrack{
+			stack:           NewStack(r.PhysicalID, et.stack.cf, et.stack.uploader),
+			trackingStarted: true,
+			path:            path,
+			since:           since,
+		}
+
+		delete(et.pendingNested, r.LogicalID)
+	}
+
+	return nil
+}
+
+func (et *EventsTrack) nestedIDs() []string {
+	ids := make([]string, 0, len(et.nested))
+	for id := range et.nested {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+// isOwnEvent tells whether the event refers to the tracked stack itself.
+func (et *EventsTrack) isOwnEvent(e StackEvent) bool {
+	return e.LogicalResourceID == et.stack.Name || e.PhysicalResourceID == et.stack.Name
 }
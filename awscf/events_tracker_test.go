@@ -0,0 +1,75 @@
+package awscf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFreshEventsIncludeEventsOfNestedStacks(t *testing.T) {
+	start := time.Now()
+	event := func(id, logicalID, resType, physicalID string, offset int) *cloudformation.StackEvent {
+		return &cloudformation.StackEvent{
+			EventId:            aws.String(id),
+			LogicalResourceId:  aws.String(logicalID),
+			ResourceType:       aws.String(resType),
+			PhysicalResourceId: aws.String(physicalID),
+			ResourceStatus:     aws.String("CREATE_IN_PROGRESS"),
+			Timestamp:          aws.Time(start.Add(time.Duration(offset) * time.Second)),
+		}
+	}
+
+	cf := &cfMock{
+		stackEvents: map[string][]*cloudformation.StackEvent{
+			"arn:child": {
+				event("c1", "child-stack", nestedStackType, "arn:child", 2),
+				event("c0", "Topic", "AWS::SNS::Topic", "", -10),
+			},
+		},
+		stackResources: []*cloudformation.StackResource{{
+			LogicalResourceId:  aws.String("Child"),
+			PhysicalResourceId: aws.String("arn:child"),
+			ResourceType:       aws.String(nestedStackType),
+			ResourceStatus:     aws.String("CREATE_IN_PROGRESS"),
+			Timestamp:          aws.Time(start),
+		}},
+	}
+
+	stack := NewStack("mystack", cf, nil)
+
+	_, err := stack.EventsTrack().FreshEvents()
+	require.NoError(t, err)
+
+	cf.stackEvents["mystack"] = []*cloudformation.StackEvent{
+		event("p1", "Child", nestedStackType, "", 1),
+		event("p0", "mystack", nestedStackType, "arn:mystack", 0),
+	}
+
+	events, err := stack.EventsTrack().FreshEvents()
+	require.NoError(t, err)
+
+	ids := []string{}
+	for _, e := range events {
+		ids = append(ids, e.NestedStack+":"+e.ID)
+	}
+
+	// the event of the nested stack that is older than the nested stack
+	// resource of the parent isn't fresh
+	assert.Equal(t, []string{"Child:c1", ":p1", ":p0"}, ids)
+	assert.Len(t, stack.EventsTrack().Collected(), 2)
+
+	cf.stackEvents["arn:child"] = append(
+		[]*cloudformation.StackEvent{event("c2", "Topic", "AWS::SNS::Topic", "", 3)},
+		cf.stackEvents["arn:child"]...,
+	)
+
+	events, err = stack.EventsTrack().FreshEvents()
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "c2", events[0].ID)
+	assert.Equal(t, "Child", events[0].NestedStack)
+}
@@ -0,0 +1,71 @@
+package awscf
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// message of the error returned by ListImports if the export isn't imported
+const notImportedMsg = "is not imported by any stack"
+
+// ExportConsumers returns the names of the stacks importing (Fn::ImportValue)
+// the values exported by the stack. The result maps export names to the
+// names of the importing stacks. The exports that aren't imported are
+// omitted.
+func (s *Stack) ExportConsumers() (map[string][]string, error) {
+	consumers := map[string][]string{}
+
+	info, err := s.Info()
+	if err != nil {
+		return consumers, err
+	}
+
+	for _, o := range info.Outputs() {
+		if o.ExportName == "" {
+			continue
+		}
+
+		importers, err := s.importers(o.ExportName)
+		if err != nil {
+			return consumers, err
+		}
+
+		if len(importers) > 0 {
+			consumers[o.ExportName] = importers
+		}
+	}
+
+	return consumers, nil
+}
+
+func (s *Stack) importers(exportName string) ([]string, error) {
+	importers := []string{}
+	input := &cloudformation.ListImportsInput{ExportName: aws.String(exportName)}
+
+	for {
+		out, err := s.cf.ListImports(input)
+		if aerr, ok := err.(awserr.Error); ok && strings.Contains(aerr.Error(), notImportedMsg) {
+			return importers, nil
+		}
+
+		if err != nil {
+			return importers, err
+		}
+
+		importers = append(importers, aws.StringValueSlice(out.Imports)...)
+
+		if out.NextToken == nil {
+			break
+		}
+
+		input.NextToken = out.NextToken
+	}
+
+	sort.Strings(importers)
+
+	return importers, nil
+}
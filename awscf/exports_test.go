@@ -0,0 +1,26 @@
+package awscf
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportConsumers(t *testing.T) {
+	cf := &cfMock{
+		stackStatus: cloudformation.StackStatusCreateComplete,
+		outputs: []*cloudformation.Output{
+			{OutputKey: aws.String("VpcId"), ExportName: aws.String("vpc-id")},
+			{OutputKey: aws.String("SubnetId"), ExportName: aws.String("subnet-id")},
+			{OutputKey: aws.String("NotExported")},
+		},
+		imports: map[string][]string{"vpc-id": {"db", "app"}},
+	}
+
+	consumers, err := NewStack("network", cf, nil).ExportConsumers()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"vpc-id": {"app", "db"}}, consumers)
+}
@@ -49,10 +49,31 @@ func (si StackInfo) Status() string {
 	return aws.StringValue(si.awsStack.StackStatus)
 }
 
+// InProgress tells whether an operation (e.g. update) is in progress on the
+// stack.
+func (si StackInfo) InProgress() bool {
+	return isInProgress(si.Status())
+}
+
+// TerminationProtection tells whether the stack is protected from deletion.
+func (si StackInfo) TerminationProtection() bool {
+	return aws.BoolValue(si.awsStack.EnableTerminationProtection)
+}
+
 func (si StackInfo) StatusDescription() string {
 	return aws.StringValue(si.awsStack.StackStatusReason)
 }
 
+// LastUpdatedTime returns the time when the stack was updated last time. The
+// creation time is returned if the stack has never been updated.
+func (si StackInfo) LastUpdatedTime() time.Time {
+	if si.awsStack.LastUpdatedTime != nil {
+		return aws.TimeValue(si.awsStack.LastUpdatedTime)
+	}
+
+	return aws.TimeValue(si.awsStack.CreationTime)
+}
+
 func (si StackInfo) Parameters() []KeyVal {
 	parameters := make([]KeyVal, 0, len(si.awsStack.Parameters))
 
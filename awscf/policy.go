@@ -0,0 +1,215 @@
+package awscf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+const resourcePrefix = "LogicalResourceId/"
+
+// allowAllStatement is the statement of the policy that doesn't protect any
+// resource. Once a stack policy is set, all the resources are protected
+// unless they are explicitly allowed to be updated.
+var allowAllStatement = map[string]interface{}{
+	"Effect":    "Allow",
+	"Action":    "Update:*",
+	"Principal": "*",
+	"Resource":  "*",
+}
+
+type policyDoc struct {
+	Statement []map[string]interface{}
+}
+
+// NormalizePolicy parses the stack policy written either in json or in yaml
+// and returns its json representation.
+func NormalizePolicy(policy string) (string, error) {
+	doc, err := parsePolicy(policy)
+	if err != nil {
+		return "", err
+	}
+
+	return doc.json()
+}
+
+// BlockingPolicy merges the statements which prevent the blocked resources
+// from deletion and replacement into the policy. All updates are allowed if
+// the policy is empty.
+func BlockingPolicy(policy string, blocked []string) (string, error) {
+	doc := policyDoc{Statement: []map[string]interface{}{allowAllStatement}}
+
+	if policy != "" {
+		var err error
+		if doc, err = parsePolicy(policy); err != nil {
+			return "", err
+		}
+	}
+
+	if len(blocked) > 0 {
+		resources := make([]interface{}, len(blocked))
+		for i, r := range blocked {
+			resources[i] = resourcePrefix + r
+		}
+
+		doc.Statement = append(doc.Statement, map[string]interface{}{
+			"Effect":    "Deny",
+			"Action":    []interface{}{"Update:Replace", "Update:Delete"},
+			"Principal": "*",
+			"Resource":  resources,
+		})
+	}
+
+	return doc.json()
+}
+
+func parsePolicy(policy string) (policyDoc, error) {
+	doc := policyDoc{}
+
+	parsed, err := ParseTemplate(policy)
+	if err != nil {
+		return doc, fmt.Errorf("invalid stack policy: %w", err)
+	}
+
+	root, ok := parsed.(map[string]interface{})
+	if !ok {
+		return doc, errors.New("invalid stack policy: policy should be an object")
+	}
+
+	statements, ok := root["Statement"].([]interface{})
+	if !ok {
+		return doc, errors.New("invalid stack policy: Statement should be a list")
+	}
+
+	for _, s := range statements {
+		statement, ok := s.(map[string]interface{})
+		if !ok {
+			return doc, errors.New("invalid stack policy: statement should be an object")
+		}
+
+		doc.Statement = append(doc.Statement, statement)
+	}
+
+	return doc, nil
+}
+
+func (doc policyDoc) json() (string, error) {
+	buf, err := json.Marshal(doc)
+	return string(buf), err
+}
+
+// Policy returns the stack policy. Empty string is returned if the stack has
+// no policy.
+func (s *Stack) Policy() (string, error) {
+	out, err := s.cf.GetStackPolicy(&cloudformation.GetStackPolicyInput{
+		StackName: aws.String(s.Name),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(out.StackPolicyBody), nil
+}
+
+// SetPolicy replaces the stack policy.
+func (s *Stack) SetPolicy(policy string) error {
+	_, err := s.cf.SetStackPolicy(&cloudformation.SetStackPolicyInput{
+		StackName:       aws.String(s.Name),
+		StackPolicyBody: aws.String(policy),
+	})
+
+	return err
+}
+
+// UnblockResource discards the blocking from the resource. The resource is
+// removed from the deny statements of the current stack policy.
+func (s *Stack) UnblockResource(resource string) error {
+	policy, err := s.Policy()
+	if err != nil {
+		return err
+	}
+
+	if policy == "" {
+		return fmt.Errorf("resource %s of stack %s is not blocked", resource, s.Name)
+	}
+
+	doc, err := parsePolicy(policy)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	statements := make([]map[string]interface{}, 0, len(doc.Statement))
+
+	for _, st := range doc.Statement {
+		if st["Effect"] == "Deny" {
+			var removed bool
+			if st, removed = withoutResource(st, resourcePrefix+resource); removed {
+				found = true
+			}
+
+			if st == nil {
+				continue
+			}
+		}
+
+		statements = append(statements, st)
+	}
+
+	if !found {
+		return fmt.Errorf("resource %s of stack %s is not blocked", resource, s.Name)
+	}
+
+	doc.Statement = statements
+
+	unblocked, err := doc.json()
+	if err != nil {
+		return err
+	}
+
+	return s.SetPolicy(unblocked)
+}
+
+// withoutResource removes the resource from the statement. Nil is returned if
+// the statement refers to no other resources.
+func withoutResource(st map[string]interface{}, resource string) (map[string]interface{}, bool) {
+	switch r := st["Resource"].(type) {
+	case string:
+		if strings.EqualFold(r, resource) {
+			return nil, true
+		}
+	case []interface{}:
+		kept := make([]interface{}, 0, len(r))
+
+		for _, res := range r {
+			if str, ok := res.(string); ok && strings.EqualFold(str, resource) {
+				continue
+			}
+
+			kept = append(kept, res)
+		}
+
+		if len(kept) == len(r) {
+			return st, false
+		}
+
+		if len(kept) == 0 {
+			return nil, true
+		}
+
+		copied := make(map[string]interface{}, len(st))
+		for k, v := range st {
+			copied[k] = v
+		}
+
+		copied["Resource"] = kept
+
+		return copied, true
+	}
+
+	return st, false
+}
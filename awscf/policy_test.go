@@ -0,0 +1,97 @@
+package awscf
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockingPolicyAllowsEverythingElse(t *testing.T) {
+	policy, err := BlockingPolicy("", []string{"Db"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"},
+		{"Effect": "Deny", "Action": ["Update:Replace", "Update:Delete"], "Principal": "*",
+			"Resource": ["LogicalResourceId/Db"]}
+	]}`, policy)
+}
+
+func TestUnblockResource(t *testing.T) {
+	policy, err := BlockingPolicy("", []string{"Db", "Queue"})
+	require.NoError(t, err)
+
+	cf := &cfMock{stackPolicy: aws.String(policy)}
+	stack := NewStack("mystack", cf, nil)
+
+	require.NoError(t, stack.UnblockResource("Db"))
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"},
+		{"Effect": "Deny", "Action": ["Update:Replace", "Update:Delete"], "Principal": "*",
+			"Resource": ["LogicalResourceId/Queue"]}
+	]}`, *cf.stackPolicy)
+
+	require.NoError(t, stack.UnblockResource("Queue"))
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"}
+	]}`, *cf.stackPolicy)
+
+	assert.EqualError(t, stack.UnblockResource("Queue"), "resource Queue of stack mystack is not blocked")
+}
+
+func TestBlockedResourcesAreMergedIntoPolicy(t *testing.T) {
+	policy, err := BlockingPolicy(`
+Statement:
+  - Effect: Deny
+    Action: Update:*
+    Principal: "*"
+    Resource: LogicalResourceId/Queue
+  - Effect: Allow
+    Action: Update:*
+    Principal: "*"
+    Resource: "*"`, []string{"Db"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Deny", "Action": "Update:*", "Principal": "*", "Resource": "LogicalResourceId/Queue"},
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"},
+		{"Effect": "Deny", "Action": ["Update:Replace", "Update:Delete"], "Principal": "*",
+			"Resource": ["LogicalResourceId/Db"]}
+	]}`, policy)
+}
+
+func TestPolicyIsNotChangedWithoutBlockedResources(t *testing.T) {
+	policy, err := BlockingPolicy(`{"Statement": [{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"}]}`, nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"}
+	]}`, policy)
+}
+
+func TestInvalidPolicyIsNotMerged(t *testing.T) {
+	_, err := BlockingPolicy(`Statement: {Effect: Allow}`, []string{"Db"})
+	assert.EqualError(t, err, "invalid stack policy: Statement should be a list")
+
+	_, err = BlockingPolicy(`Statement: [Allow]`, []string{"Db"})
+	assert.EqualError(t, err, "invalid stack policy: statement should be an object")
+}
+
+func TestUnblockResourceOfCustomPolicy(t *testing.T) {
+	cf := &cfMock{stackPolicy: aws.String(`{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"},
+		{"Effect": "Deny", "Action": "Update:*", "Principal": "*", "Resource": "LogicalResourceId/Db"},
+		{"Effect": "Deny", "Action": "Update:Delete", "Principal": "*", "Resource": ["LogicalResourceId/db", "LogicalResourceId/Queue"]}
+	]}`)}
+	stack := NewStack("mystack", cf, nil)
+
+	require.NoError(t, stack.UnblockResource("Db"))
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"},
+		{"Effect": "Deny", "Action": "Update:Delete", "Principal": "*", "Resource": ["LogicalResourceId/Queue"]}
+	]}`, *cf.stackPolicy)
+}
+
+func TestUnblockResourceOfStackWithoutPolicy(t *testing.T) {
+	stack := NewStack("mystack", &cfMock{}, nil)
+	assert.EqualError(t, stack.UnblockResource("Db"), "resource Db of stack mystack is not blocked")
+}
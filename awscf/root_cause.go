@@ -0,0 +1,112 @@
+package awscf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	nestedStackType = "AWS::CloudFormation::Stack"
+
+	// maxNestingDepth limits how deep the nested stacks are analyzed
+	maxNestingDepth = 5
+)
+
+// RootCause is the event that caused the failure of the stack operation.
+type RootCause struct {
+	// StackName is the name (or ID) of the stack where the failure happened.
+	// It differs from the name of the synchronized stack if the failure
+	// happened in a nested stack.
+	StackName string
+	Event     StackEvent
+}
+
+// FailureError is the error of the stack operation extended with its root
+// cause.
+type FailureError struct {
+	Err       error
+	RootCause RootCause
+}
+
+func (e *FailureError) Error() string {
+	ev := e.RootCause.Event
+
+	return fmt.Sprintf("%s\nRoot cause:\n  Stack:    %s\n  Resource: %s (%s)\n  Status:   %s\n  Reason:   %s",
+		e.Err.Error(), e.RootCause.StackName, ev.LogicalResourceID, ev.ResourceType, ev.Status, ev.StatusReason)
+}
+
+func (e *FailureError) Unwrap() error {
+	return e.Err
+}
+
+// ExplainFailure extends the error of the stack operation with the root cause
+// found among the events collected by the events track. The error is
+// returned as is if the root cause isn't found.
+func (s *Stack) ExplainFailure(err error) error {
+	rc, found := s.rootCause(s.EventsTrack().Collected(), 0)
+	if !found {
+		return err
+	}
+
+	return &FailureError{Err: err, RootCause: rc}
+}
+
+func (s *Stack) rootCause(events StackEvents, depth int) (RootCause, bool) {
+	var failed *StackEvent
+
+	// events are ordered from newest to oldest
+	for i := len(events) - 1; i >= 0; i-- {
+		if isRootCauseCandidate(events[i]) {
+			failed = &events[i]
+			break
+		}
+	}
+
+	if failed == nil {
+		return RootCause{}, false
+	}
+
+	rc := RootCause{StackName: s.Name, Event: *failed}
+
+	if failed.ResourceType != nestedStackType || failed.PhysicalResourceID == "" ||
+		failed.PhysicalResourceID == s.Name || depth >= maxNestingDepth {
+		return rc, true
+	}
+
+	nested := NewStack(failed.PhysicalResourceID, s.cf, s.uploader)
+
+	nestedEvents, err := nested.Events()
+	if err != nil {
+		return rc, true
+	}
+
+	// only the events of the failed operation are relevant
+	since := events[len(events)-1].Timestamp
+	nestedEvents = eventsSince(nestedEvents, since)
+
+	if nestedRC, found := nested.rootCause(nestedEvents, depth+1); found {
+		return nestedRC, true
+	}
+
+	return rc, true
+}
+
+// isRootCauseCandidate tells whether the event is a failure which isn't a
+// consequence of another failure.
+func isRootCauseCandidate(e StackEvent) bool {
+	return strings.HasSuffix(e.Status, "_FAILED") &&
+		!strings.Contains(strings.ToLower(e.StatusReason), "cancelled")
+}
+
+func eventsSince(events StackEvents, since time.Time) StackEvents {
+	filtered := make(StackEvents, 0, len(events))
+
+	for _, e := range events {
+		if !e.Timestamp.Before(since) {
+			filtered = append(filtered, e)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,83 @@
+package awscf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCauseIsFirstFailureThatIsNotCancellation(t *testing.T) {
+	start := time.Now()
+	events := StackEvents{
+		{ID: "4", LogicalResourceID: "mystack", Status: "UPDATE_ROLLBACK_IN_PROGRESS", Timestamp: start.Add(4 * time.Second)},
+		{ID: "3", LogicalResourceID: "Queue", Status: "UPDATE_FAILED", StatusReason: "Resource update cancelled",
+			Timestamp: start.Add(3 * time.Second)},
+		{ID: "2", LogicalResourceID: "Bucket", ResourceType: "AWS::S3::Bucket", Status: "UPDATE_FAILED",
+			StatusReason: "Access denied", Timestamp: start.Add(2 * time.Second)},
+		{ID: "1", LogicalResourceID: "Bucket", Status: "UPDATE_IN_PROGRESS", Timestamp: start.Add(time.Second)},
+	}
+
+	stack := NewStack("mystack", &cfMock{}, nil)
+	stack.EventsTrack().collected = events
+
+	err := stack.ExplainFailure(errors.New("update failed"))
+
+	var ferr *FailureError
+
+	require.True(t, errors.As(err, &ferr))
+	assert.Equal(t, RootCause{StackName: "mystack", Event: events[2]}, ferr.RootCause)
+	assert.Equal(t, `update failed
+Root cause:
+  Stack:    mystack
+  Resource: Bucket (AWS::S3::Bucket)
+  Status:   UPDATE_FAILED
+  Reason:   Access denied`, err.Error())
+}
+
+func TestRootCauseIsSearchedInNestedStack(t *testing.T) {
+	start := time.Now()
+	cf := &cfMock{}
+	cf.describeStackEventsFunc = func() (*cloudformation.DescribeStackEventsOutput, error) {
+		return &cloudformation.DescribeStackEventsOutput{StackEvents: []*cloudformation.StackEvent{{
+			EventId:              aws.String("old"),
+			LogicalResourceId:    aws.String("Topic"),
+			ResourceStatus:       aws.String("CREATE_FAILED"),
+			ResourceStatusReason: aws.String("failure of previous operation"),
+			Timestamp:            aws.Time(start.Add(-time.Hour)),
+		}, {
+			EventId:              aws.String("n2"),
+			LogicalResourceId:    aws.String("Role"),
+			ResourceType:         aws.String("AWS::IAM::Role"),
+			ResourceStatus:       aws.String("UPDATE_FAILED"),
+			ResourceStatusReason: aws.String("Invalid policy"),
+			Timestamp:            aws.Time(start.Add(2 * time.Second)),
+		}}}, nil
+	}
+
+	stack := NewStack("mystack", cf, nil)
+	stack.EventsTrack().collected = StackEvents{
+		{ID: "2", LogicalResourceID: "Child", ResourceType: "AWS::CloudFormation::Stack", Status: "UPDATE_FAILED",
+			PhysicalResourceID: "arn:child", StatusReason: "Embedded stack was not successfully updated",
+			Timestamp: start.Add(3 * time.Second)},
+		{ID: "1", LogicalResourceID: "Child", Status: "UPDATE_IN_PROGRESS", Timestamp: start},
+	}
+
+	err := stack.ExplainFailure(errors.New("update failed"))
+
+	var ferr *FailureError
+
+	require.True(t, errors.As(err, &ferr))
+	assert.Equal(t, "arn:child", ferr.RootCause.StackName)
+	assert.Equal(t, "Role", ferr.RootCause.Event.LogicalResourceID)
+	assert.Equal(t, "Invalid policy", ferr.RootCause.Event.StatusReason)
+}
+
+func TestErrorIsReturnedAsIsWithoutRootCause(t *testing.T) {
+	err := errors.New("update failed")
+	assert.Equal(t, err, NewStack("mystack", &cfMock{}, nil).ExplainFailure(err))
+}
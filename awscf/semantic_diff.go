@@ -0,0 +1,230 @@
+package awscf
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kinds of the template changes.
+const (
+	TemplateValueAdded    = "added"
+	TemplateValueRemoved  = "removed"
+	TemplateValueModified = "modified"
+)
+
+// TemplateChange is a change of a single value of the template found by the
+// semantic diff.
+type TemplateChange struct {
+	// Path is the path of the changed value, e.g.
+	// Resources.Bucket.Properties.Tags[0].Value.
+	Path string
+
+	// Section is the top level section of the template containing the
+	// change, e.g. Resources.
+	Section string
+
+	Kind string
+	Old  interface{}
+	New  interface{}
+}
+
+// DiffTemplates compares the templates structurally. Both json and yaml
+// templates are supported, formatting and order of keys don't matter. The
+// changes are sorted by path. An empty template (e.g. the one of the stack that
+// is not deployed yet) has no sections, so every section of the other template
+// is reported as added or removed.
+func DiffTemplates(oldBody, newBody string) ([]TemplateChange, error) {
+	oldTpl, err := parseDiffedTemplate(oldBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse deployed template: %v", err)
+	}
+
+	newTpl, err := parseDiffedTemplate(newBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse new template: %v", err)
+	}
+
+	changes := []TemplateChange{}
+	diffValues("", oldTpl, newTpl, &changes)
+
+	return changes, nil
+}
+
+func parseDiffedTemplate(body string) (interface{}, error) {
+	tpl, err := ParseTemplate(body)
+	if err != nil || tpl != nil {
+		return tpl, err
+	}
+
+	return map[string]interface{}{}, nil
+}
+
+func diffValues(path string, oldVal, newVal interface{}, changes *[]TemplateChange) {
+	switch {
+	case oldVal == nil && newVal == nil:
+		return
+	case oldVal == nil:
+		addTemplateChange(changes, path, TemplateValueAdded, nil, newVal)
+		return
+	case newVal == nil:
+		addTemplateChange(changes, path, TemplateValueRemoved, oldVal, nil)
+		return
+	}
+
+	oldMap, oldIsMap := oldVal.(map[string]interface{})
+	newMap, newIsMap := newVal.(map[string]interface{})
+
+	if oldIsMap && newIsMap {
+		diffMaps(path, oldMap, newMap, changes)
+		return
+	}
+
+	oldList, oldIsList := oldVal.([]interface{})
+	newList, newIsList := newVal.([]interface{})
+
+	if oldIsList && newIsList {
+		diffLists(path, oldList, newList, changes)
+		return
+	}
+
+	if !templateValuesEqual(oldVal, newVal) {
+		addTemplateChange(changes, path, TemplateValueModified, oldVal, newVal)
+	}
+}
+
+// templateValuesEqual compares the values of the templates. CloudFormation
+// treats the scalars as strings, so "80" equals 80 and "true" equals true.
+func templateValuesEqual(a, b interface{}) bool {
+	switch aVal := a.(type) {
+	case map[string]interface{}:
+		bVal, ok := b.(map[string]interface{})
+		if !ok || len(aVal) != len(bVal) {
+			return false
+		}
+
+		for k, v := range aVal {
+			bv, ok := bVal[k]
+			if !ok || !templateValuesEqual(v, bv) {
+				return false
+			}
+		}
+
+		return true
+	case []interface{}:
+		bVal, ok := b.([]interface{})
+		if !ok || len(aVal) != len(bVal) {
+			return false
+		}
+
+		for i := range aVal {
+			if !templateValuesEqual(aVal[i], bVal[i]) {
+				return false
+			}
+		}
+
+		return true
+	case nil:
+		return b == nil
+	}
+
+	switch b.(type) {
+	case map[string]interface{}, []interface{}, nil:
+		return false
+	}
+
+	return scalarString(a) == scalarString(b)
+}
+
+func scalarString(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
+func diffMaps(path string, oldMap, newMap map[string]interface{}, changes *[]TemplateChange) {
+	keys := make([]string, 0, len(oldMap)+len(newMap))
+
+	for k := range oldMap {
+		keys = append(keys, k)
+	}
+
+	for k := range newMap {
+		if _, ok := oldMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		keyPath := k
+		if path != "" {
+			keyPath = path + "." + k
+		}
+
+		oldVal, inOld := oldMap[k]
+		newVal, inNew := newMap[k]
+
+		switch {
+		case !inOld:
+			addTemplateChange(changes, keyPath, TemplateValueAdded, nil, newVal)
+		case !inNew:
+			addTemplateChange(changes, keyPath, TemplateValueRemoved, oldVal, nil)
+		default:
+			diffValues(keyPath, oldVal, newVal, changes)
+		}
+	}
+}
+
+func diffLists(path string, oldList, newList []interface{}, changes *[]TemplateChange) {
+	for i := 0; i < len(oldList) || i < len(newList); i++ {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+
+		switch {
+		case i >= len(oldList):
+			addTemplateChange(changes, itemPath, TemplateValueAdded, nil, newList[i])
+		case i >= len(newList):
+			addTemplateChange(changes, itemPath, TemplateValueRemoved, oldList[i], nil)
+		default:
+			diffValues(itemPath, oldList[i], newList[i], changes)
+		}
+	}
+}
+
+func addTemplateChange(changes *[]TemplateChange, path, kind string, oldVal, newVal interface{}) {
+	section := path
+	if i := strings.IndexAny(path, ".["); i >= 0 {
+		section = path[:i]
+	}
+
+	*changes = append(*changes, TemplateChange{
+		Path:    path,
+		Section: section,
+		Kind:    kind,
+		Old:     oldVal,
+		New:     newVal,
+	})
+}
+
+// FormatTemplateValue formats the value of the template in a compact form:
+// scalars as they are, maps and lists as json.
+func FormatTemplateValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}, []interface{}:
+		buf, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+
+		return string(buf)
+	}
+
+	return fmt.Sprintf("%v", v)
+}
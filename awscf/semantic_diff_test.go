@@ -0,0 +1,154 @@
+package awscf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReformattedTemplateHasNoSemanticChanges(t *testing.T) {
+	oldBody := `{"Resources": {"Topic": {"Type": "AWS::SNS::Topic",
+		"Properties": {"TopicName": {"Fn::Sub": "${Env}-topic"}, "DisplayName": "topic"}}}}`
+	newBody := `
+Resources:
+  Topic:
+    Properties:
+      DisplayName: topic
+      TopicName: !Sub "${Env}-topic"
+    Type: AWS::SNS::Topic
+`
+
+	changes, err := DiffTemplates(oldBody, newBody)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestSectionsOfNewStackAreAdded(t *testing.T) {
+	newBody := `
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+Outputs:
+  TopicArn:
+    Value: !Ref Topic
+`
+
+	changes, err := DiffTemplates("", newBody)
+	require.NoError(t, err)
+
+	assert.Equal(t, []TemplateChange{{
+		Path:    "Outputs",
+		Section: "Outputs",
+		Kind:    TemplateValueAdded,
+		New:     map[string]interface{}{"TopicArn": map[string]interface{}{"Value": map[string]interface{}{"Ref": "Topic"}}},
+	}, {
+		Path:    "Resources",
+		Section: "Resources",
+		Kind:    TemplateValueAdded,
+		New:     map[string]interface{}{"Topic": map[string]interface{}{"Type": "AWS::SNS::Topic"}},
+	}}, changes)
+}
+
+func TestTemplateChangesAreReportedByPath(t *testing.T) {
+	oldBody := `
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      VersioningConfiguration:
+        Status: Suspended
+      Tags:
+        - Key: env
+          Value: dev
+Outputs:
+  BucketName:
+    Value: !Ref Bucket
+`
+	newBody := `
+Resources:
+  Bucket:
+    Type: AWS::S3::Bucket
+    Properties:
+      VersioningConfiguration:
+        Status: Enabled
+      Tags:
+        - Key: env
+          Value: prod
+        - Key: team
+          Value: core
+  Queue:
+    Type: AWS::SQS::Queue
+`
+
+	changes, err := DiffTemplates(oldBody, newBody)
+	require.NoError(t, err)
+
+	expected := []TemplateChange{{
+		Path:    "Outputs",
+		Section: "Outputs",
+		Kind:    TemplateValueRemoved,
+		Old: map[string]interface{}{
+			"BucketName": map[string]interface{}{"Value": map[string]interface{}{"Ref": "Bucket"}},
+		},
+	}, {
+		Path:    "Resources.Bucket.Properties.Tags[0].Value",
+		Section: "Resources",
+		Kind:    TemplateValueModified,
+		Old:     "dev",
+		New:     "prod",
+	}, {
+		Path:    "Resources.Bucket.Properties.Tags[1]",
+		Section: "Resources",
+		Kind:    TemplateValueAdded,
+		New:     map[string]interface{}{"Key": "team", "Value": "core"},
+	}, {
+		Path:    "Resources.Bucket.Properties.VersioningConfiguration.Status",
+		Section: "Resources",
+		Kind:    TemplateValueModified,
+		Old:     "Suspended",
+		New:     "Enabled",
+	}, {
+		Path:    "Resources.Queue",
+		Section: "Resources",
+		Kind:    TemplateValueAdded,
+		New:     map[string]interface{}{"Type": "AWS::SQS::Queue"},
+	}}
+	assert.Equal(t, expected, changes)
+}
+
+func TestScalarsAreComparedAsStrings(t *testing.T) {
+	oldBody := `{"Resources": {"SG": {"Properties": {"Port": "80", "Public": "true", "Ratio": "0.5"}}}}`
+	newBody := `
+Resources:
+  SG:
+    Properties:
+      Port: 80
+      Public: true
+      Ratio: 0.5
+`
+
+	changes, err := DiffTemplates(oldBody, newBody)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	resources, err := diffResources(oldBody, newBody)
+	require.NoError(t, err)
+	assert.Empty(t, resources)
+}
+
+func TestScalarChangesAreReported(t *testing.T) {
+	changes, err := DiffTemplates(`{"Port": "80"}`, `Port: 8080`)
+	require.NoError(t, err)
+	assert.Equal(t, []TemplateChange{{
+		Path:    "Port",
+		Section: "Port",
+		Kind:    TemplateValueModified,
+		Old:     "80",
+		New:     8080,
+	}}, changes)
+
+	changes, err = DiffTemplates(`{"Port": ["80"]}`, `Port: 80`)
+	require.NoError(t, err)
+	assert.Len(t, changes, 1)
+}
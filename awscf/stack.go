@@ -1,12 +1,14 @@
 package awscf
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
@@ -18,12 +20,18 @@ const noChangeStatus = "The submitted information didn't contain changes. " +
 
 // StackEvent is a stack event.
 type StackEvent struct {
-	ID                string
-	ResourceType      string
-	Status            string
-	LogicalResourceID string
-	StatusReason      string
-	Timestamp         time.Time
+	ID                 string
+	ResourceType       string
+	Status             string
+	LogicalResourceID  string
+	PhysicalResourceID string
+	StatusReason       string
+	Timestamp          time.Time
+
+	// NestedStack is the path of logical IDs of the nested stack the event
+	// belongs to (e.g. "Network/Subnets"). It's empty for the events of the
+	// stack itself.
+	NestedStack string
 }
 
 type StackEvents []StackEvent
@@ -41,6 +49,10 @@ var ErrNoChange = errors.New("no changes")
 
 var ErrStackDoesntExist = errors.New("stack doesn't exist")
 
+// ErrChangeSetNotFound is error that indicates that the change set isn't
+// registered in the stack.
+var ErrChangeSetNotFound = errors.New("change set is not found")
+
 type Stack struct {
 	Name string
 
@@ -48,12 +60,25 @@ type Stack struct {
 	uploader    *saAws.S3Uploader
 	cachedInfo  *StackInfo
 	eventsTrack *EventsTrack
+	waitOpts    WaitOpts
 }
 
 func NewStack(name string, cf cloudformationiface.CloudFormationAPI, uploader *saAws.S3Uploader) *Stack {
 	return &Stack{Name: name, cf: cf, uploader: uploader}
 }
 
+// WithWaitOpts sets up how the completion of the stack operations is waited
+// for.
+func (s *Stack) WithWaitOpts(opts WaitOpts) *Stack {
+	s.waitOpts = opts
+	return s
+}
+
+// PollInterval is the interval between the checks of the stack status.
+func (s *Stack) PollInterval() time.Duration {
+	return s.waitOpts.pollInterval()
+}
+
 func (s *Stack) Info() (StackInfo, error) {
 	if s.cachedInfo != nil {
 		return *s.cachedInfo, nil
@@ -72,6 +97,12 @@ func (s *Stack) Info() (StackInfo, error) {
 	return info, err
 }
 
+// FreshInfo is the same as Info except that cached info is not used.
+func (s *Stack) FreshInfo() (StackInfo, error) {
+	s.cachedInfo = nil
+	return s.Info()
+}
+
 func (s *Stack) describe() (*cloudformation.Stack, error) {
 	info, err := s.cf.DescribeStacks(&cloudformation.DescribeStacksInput{
 		StackName: aws.String(s.Name),
@@ -102,7 +133,9 @@ func (s *Stack) Exists() (bool, error) {
 	return true, nil
 }
 
-func (s *Stack) Delete() error {
+// Delete deletes the stack and waits until the deletion is complete. If ctx
+// is canceled, Delete stops waiting but the deletion keeps going.
+func (s *Stack) Delete(ctx context.Context) error {
 	_, err := s.cf.DeleteStack(&cloudformation.DeleteStackInput{
 		StackName: aws.String(s.Name),
 	})
@@ -113,12 +146,88 @@ func (s *Stack) Delete() error {
 	waitInput := cloudformation.DescribeStacksInput{
 		StackName: aws.String(s.Name),
 	}
-	ctx := aws.BackgroundContext()
 
-	return s.cf.WaitUntilStackDeleteCompleteWithContext(ctx, &waitInput, func(w *request.Waiter) {
-		w.MaxAttempts = 900
-		w.Delay = request.ConstantWaiterDelay(2 * time.Second)
+	err = s.cf.WaitUntilStackDeleteCompleteWithContext(ctx, &waitInput, s.waitOpts.stackWaiter())
+
+	// the stack is gone (or is being deleted), the cached info is stale
+	s.cachedInfo = nil
+
+	return timeoutError(err, s.cf, s.Name, s.waitOpts.stackTimeout())
+}
+
+// SetTerminationProtection enables or disables the protection of the stack
+// from deletion.
+func (s *Stack) SetTerminationProtection(enabled bool) error {
+	_, err := s.cf.UpdateTerminationProtection(&cloudformation.UpdateTerminationProtectionInput{
+		StackName:                   aws.String(s.Name),
+		EnableTerminationProtection: aws.Bool(enabled),
 	})
+
+	// the cached info is stale
+	s.cachedInfo = nil
+
+	return err
+}
+
+// CancelUpdate cancels the update of the stack that is in progress. The stack
+// is rolled back to its previous state.
+func (s *Stack) CancelUpdate() error {
+	_, err := s.cf.CancelUpdateStack(&cloudformation.CancelUpdateStackInput{
+		StackName: aws.String(s.Name),
+	})
+
+	return err
+}
+
+// ContinueUpdateRollback continues rolling back the stack that is in
+// UPDATE_ROLLBACK_FAILED state and waits until the rollback is complete. The
+// resources that can't be rolled back can be skipped.
+func (s *Stack) ContinueUpdateRollback(ctx context.Context, resourcesToSkip []string) error {
+	input := &cloudformation.ContinueUpdateRollbackInput{
+		StackName: aws.String(s.Name),
+	}
+
+	if len(resourcesToSkip) > 0 {
+		input.ResourcesToSkip = aws.StringSlice(resourcesToSkip)
+	}
+
+	if _, err := s.cf.ContinueUpdateRollback(input); err != nil {
+		return err
+	}
+
+	w := request.Waiter{
+		Name: "WaitUntilStackUpdateRollbackComplete",
+		Acceptors: []request.WaiterAcceptor{
+			{
+				State:    request.SuccessWaiterState,
+				Matcher:  request.PathAllWaiterMatch,
+				Argument: "Stacks[].StackStatus",
+				Expected: cloudformation.StackStatusUpdateRollbackComplete,
+			},
+			{
+				State:    request.FailureWaiterState,
+				Matcher:  request.PathAnyWaiterMatch,
+				Argument: "Stacks[].StackStatus",
+				Expected: cloudformation.StackStatusUpdateRollbackFailed,
+			},
+		},
+		NewRequest: func(opts []request.Option) (*request.Request, error) {
+			req, _ := s.cf.DescribeStacksRequest(&cloudformation.DescribeStacksInput{
+				StackName: aws.String(s.Name),
+			})
+			req.SetContext(ctx)
+			req.ApplyOptions(opts...)
+
+			return req, nil
+		},
+	}
+	w.ApplyOptions(s.waitOpts.stackWaiter())
+
+	err := w.WaitWithContext(ctx)
+
+	s.cachedInfo = nil
+
+	return timeoutError(err, s.cf, s.Name, s.waitOpts.stackTimeout())
 }
 
 func (s *Stack) AlreadyDeployed() (bool, error) {
@@ -139,6 +248,87 @@ func (s *Stack) AlreadyDeployed() (bool, error) {
 	return !info.InReviewState(), nil
 }
 
+// LoadChangeSet loads the change set that has been previously registered in
+// the stack. An error is returned if the change set can't be executed.
+func (s *Stack) LoadChangeSet(id string, isUpdate bool) (*ChangeSetHandle, error) {
+	chSet := &ChangeSetHandle{
+		ID:        id,
+		IsUpdate:  isUpdate,
+		cf:        s.cf,
+		stackName: s.Name,
+		waitOpts:  s.waitOpts,
+	}
+
+	if err := chSet.loadChanges(); err != nil {
+		return chSet, err
+	}
+
+	if chSet.ExecutionStatus != cloudformation.ExecutionStatusAvailable {
+		return chSet, fmt.Errorf("change set %s can't be executed. Execution status: %s", id, chSet.ExecutionStatus)
+	}
+
+	return chSet, nil
+}
+
+// RegisteredChangeSet returns the change set as it's registered in
+// cloudformation: with its template, parameters and tags. It allows to diff
+// the change set that is loaded rather than created by this process.
+func (s *Stack) RegisteredChangeSet(chSet *ChangeSetHandle) (*ChangeSet, error) {
+	tpl, err := s.cf.GetTemplate(&cloudformation.GetTemplateInput{
+		ChangeSetName: aws.String(chSet.ID),
+		TemplateStage: aws.String(cloudformation.TemplateStageOriginal),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]string, len(chSet.Parameters))
+	for _, p := range chSet.Parameters {
+		params[p.Key] = p.Val
+	}
+
+	tags := make(map[string]string, len(chSet.Tags))
+	for _, t := range chSet.Tags {
+		tags[t.Key] = t.Val
+	}
+
+	return s.ChangeSet(aws.StringValue(tpl.TemplateBody)).WithParameters(params).WithTags(tags), nil
+}
+
+// LoadNamedChangeSet loads the change set that has been previously registered
+// in the stack under the given name. ErrChangeSetNotFound is returned if
+// there is no such change set.
+func (s *Stack) LoadNamedChangeSet(name string) (*ChangeSetHandle, error) {
+	exists, err := s.Exists()
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, ErrChangeSetNotFound
+	}
+
+	setInfo, err := s.cf.DescribeChangeSet(&cloudformation.DescribeChangeSetInput{
+		ChangeSetName: aws.String(name),
+		StackName:     aws.String(s.Name),
+	})
+
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == cloudformation.ErrCodeChangeSetNotFoundException {
+		return nil, ErrChangeSetNotFound
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	isUpdate, err := s.AlreadyDeployed()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.LoadChangeSet(aws.StringValue(setInfo.ChangeSetId), isUpdate)
+}
+
 func (s *Stack) ChangeSet(body string) *ChangeSet {
 	return &ChangeSet{
 		stack:      s,
@@ -206,12 +396,13 @@ func (s *Stack) Events() ([]StackEvent, error) {
 
 	for i, e := range awsEvents.StackEvents {
 		events[i] = StackEvent{
-			ID:                aws.StringValue(e.EventId),
-			ResourceType:      aws.StringValue(e.ResourceType),
-			Status:            aws.StringValue(e.ResourceStatus),
-			LogicalResourceID: aws.StringValue(e.LogicalResourceId),
-			StatusReason:      aws.StringValue(e.ResourceStatusReason),
-			Timestamp:         aws.TimeValue(e.Timestamp),
+			ID:                 aws.StringValue(e.EventId),
+			ResourceType:       aws.StringValue(e.ResourceType),
+			Status:             aws.StringValue(e.ResourceStatus),
+			LogicalResourceID:  aws.StringValue(e.LogicalResourceId),
+			PhysicalResourceID: aws.StringValue(e.PhysicalResourceId),
+			StatusReason:       aws.StringValue(e.ResourceStatusReason),
+			Timestamp:          aws.TimeValue(e.Timestamp),
 		}
 	}
 
@@ -226,42 +417,85 @@ func (s *Stack) EventsTrack() *EventsTrack {
 	return s.eventsTrack
 }
 
-// BlockResource prevents a stack resource from deletion and replacement.
-func (s *Stack) BlockResource(resource string) error {
-	policy := `{
-		"Statement" : [{
-			"Effect" : "Deny",
-			"Action" : [
-				"Update:Replace",
-				"Update:Delete"
-			],
-			"Principal": "*",
-			"Resource" : "LogicalResourceId/%s"
-		}]
-	}`
-
-	return s.applyPolicy(fmt.Sprintf(policy, resource))
+// StackStateError is returned when the stack is in the state in which it
+// can't be updated until an action is taken.
+type StackStateError struct {
+	StackName string
+	Status    string
 }
 
-// UnblockResource discards the blocking from the resource.
-func (s *Stack) UnblockResource(resource string) error {
-	policy := `{
-		"Statement" : [{
-			"Effect" : "Allow",
-			"Action" : "Update:*",
-			"Principal": "*",
-			"Resource" : "LogicalResourceId/%s"
-		}]
-	}`
-
-	return s.applyPolicy(fmt.Sprintf(policy, resource))
+// InProgress tells whether the stack can't be updated because another
+// operation on the stack is in progress.
+func (e *StackStateError) InProgress() bool {
+	return isInProgress(e.Status)
 }
 
-func (s *Stack) applyPolicy(policy string) error {
-	_, err := s.cf.SetStackPolicy(&cloudformation.SetStackPolicyInput{
-		StackName:       aws.String(s.Name),
-		StackPolicyBody: aws.String(policy),
-	})
+func (e *StackStateError) Error() string {
+	if e.InProgress() {
+		return fmt.Sprintf("stack %s can't be updated since another operation is in progress. Stack status: %s",
+			e.StackName, e.Status)
+	}
 
-	return err
+	switch e.Status {
+	case cloudformation.StackStatusRollbackComplete:
+		return fmt.Sprintf("stack %s is in %s state and can't be updated. It has to be deleted and created again",
+			e.StackName, e.Status)
+	case cloudformation.StackStatusUpdateRollbackFailed:
+		return fmt.Sprintf("stack %s is in %s state and can't be updated until the rollback is continued",
+			e.StackName, e.Status)
+	}
+
+	return fmt.Sprintf("stack %s is in %s state and can't be updated", e.StackName, e.Status)
+}
+
+func (s *Stack) checkUpdatable() error {
+	info, err := s.Info()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Status() == cloudformation.StackStatusRollbackComplete,
+		info.Status() == cloudformation.StackStatusUpdateRollbackFailed,
+		info.InProgress():
+		return &StackStateError{StackName: s.Name, Status: info.Status()}
+	}
+
+	return nil
+}
+
+// WaitUntilIdle waits until the operation that is in progress on the stack is
+// complete.
+func (s *Stack) WaitUntilIdle(ctx context.Context) error {
+	deadline := time.Now().Add(s.waitOpts.stackTimeout())
+
+	for {
+		info, err := s.FreshInfo()
+		if err == ErrStackDoesntExist {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if !info.InProgress() {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			err = awserr.New(request.WaiterResourceNotReadyErrorCode, waiterExceededMsg, nil)
+			return timeoutError(err, s.cf, s.Name, s.waitOpts.stackTimeout())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.waitOpts.pollInterval()):
+		}
+	}
+}
+
+func isInProgress(status string) bool {
+	return strings.HasSuffix(status, "_IN_PROGRESS") && status != cloudformation.StackStatusReviewInProgress
 }
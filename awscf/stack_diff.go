@@ -0,0 +1,207 @@
+package awscf
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/molecule-man/stack-assembly/cli"
+)
+
+// StackDiff is the structured diff between the deployed stack and the change
+// set.
+type StackDiff struct {
+	Stack      string
+	Parameters []KeyChange
+	Tags       []KeyChange
+	Resources  []ResourceDiff
+
+	// Body is the diff of the template bodies in the mode of the ChSetDiff.
+	Body string
+}
+
+// HasChanges tells whether the change set differs from the deployed stack.
+func (sd StackDiff) HasChanges() bool {
+	return len(sd.Parameters) > 0 || len(sd.Tags) > 0 || len(sd.Resources) > 0 || sd.Body != ""
+}
+
+// KeyChange is the change of a parameter or a tag. Kind is one of
+// TemplateValueAdded, TemplateValueRemoved or TemplateValueModified.
+type KeyChange struct {
+	Key  string
+	Kind string
+	Old  string
+	New  string
+}
+
+// ResourceDiff is the change of the resource in the template. Kind is one of
+// TemplateValueAdded, TemplateValueRemoved or TemplateValueModified.
+type ResourceDiff struct {
+	LogicalResourceID string
+	ResourceType      string
+	Kind              string
+}
+
+// StackDiff builds the structured diff of the change set. The body diff is
+// never colorized.
+func (d ChSetDiff) StackDiff(chSet *ChangeSet) (StackDiff, error) {
+	sd := StackDiff{Stack: chSet.Stack().Name}
+
+	deployed, err := chSet.Stack().AlreadyDeployed()
+	if err != nil {
+		return sd, err
+	}
+
+	oldParams, oldTags := map[string]string{}, map[string]string{}
+
+	if deployed {
+		info, err := chSet.Stack().Info()
+		if err != nil {
+			return sd, err
+		}
+
+		for _, p := range info.Parameters() {
+			oldParams[p.Key] = p.Val
+		}
+
+		for _, t := range info.Tags() {
+			oldTags[t.Key] = t.Val
+		}
+	}
+
+	awsParams, err := chSet.awsParameters()
+	if err != nil {
+		return sd, err
+	}
+
+	newParams := make(map[string]string, len(awsParams))
+
+	for _, p := range awsParams {
+		key := aws.StringValue(p.ParameterKey)
+
+		if aws.BoolValue(p.UsePreviousValue) {
+			newParams[key] = oldParams[key]
+			continue
+		}
+
+		newParams[key] = aws.StringValue(p.ParameterValue)
+	}
+
+	sd.Parameters = diffKeys(oldParams, newParams)
+	sd.Tags = diffKeys(oldTags, chSet.tags)
+
+	oldBody, _, err := deployedBody(chSet)
+	if err != nil {
+		return sd, err
+	}
+
+	if sd.Resources, err = diffResources(oldBody, chSet.body); err != nil {
+		return sd, err
+	}
+
+	plain := ChSetDiff{Color: cli.Color{Disabled: true}, Mode: d.Mode}
+
+	if d.Mode == DiffModeSemantic {
+		sd.Body, err = plain.semanticDiffBody(chSet)
+	} else {
+		sd.Body, err = diffBody(chSet)
+	}
+
+	return sd, err
+}
+
+func diffKeys(oldVals, newVals map[string]string) []KeyChange {
+	keys := make([]string, 0, len(oldVals)+len(newVals))
+
+	for k := range oldVals {
+		keys = append(keys, k)
+	}
+
+	for k := range newVals {
+		if _, ok := oldVals[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	changes := []KeyChange{}
+
+	for _, k := range keys {
+		oldVal, inOld := oldVals[k]
+		newVal, inNew := newVals[k]
+
+		switch {
+		case !inOld:
+			changes = append(changes, KeyChange{Key: k, Kind: TemplateValueAdded, New: newVal})
+		case !inNew:
+			changes = append(changes, KeyChange{Key: k, Kind: TemplateValueRemoved, Old: oldVal})
+		case oldVal != newVal:
+			changes = append(changes, KeyChange{Key: k, Kind: TemplateValueModified, Old: oldVal, New: newVal})
+		}
+	}
+
+	return changes
+}
+
+func diffResources(oldBody, newBody string) ([]ResourceDiff, error) {
+	oldResources, err := templateResources(oldBody)
+	if err != nil {
+		return nil, err
+	}
+
+	newResources, err := templateResources(newBody)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(oldResources)+len(newResources))
+
+	for id := range oldResources {
+		ids = append(ids, id)
+	}
+
+	for id := range newResources {
+		if _, ok := oldResources[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	diffs := []ResourceDiff{}
+
+	for _, id := range ids {
+		oldRes, inOld := oldResources[id]
+		newRes, inNew := newResources[id]
+
+		switch {
+		case !inOld:
+			diffs = append(diffs, ResourceDiff{LogicalResourceID: id, ResourceType: resourceType(newRes), Kind: TemplateValueAdded})
+		case !inNew:
+			diffs = append(diffs, ResourceDiff{LogicalResourceID: id, ResourceType: resourceType(oldRes), Kind: TemplateValueRemoved})
+		case !templateValuesEqual(oldRes, newRes):
+			diffs = append(diffs, ResourceDiff{LogicalResourceID: id, ResourceType: resourceType(newRes), Kind: TemplateValueModified})
+		}
+	}
+
+	return diffs, nil
+}
+
+func templateResources(body string) (map[string]interface{}, error) {
+	tpl, err := ParseTemplate(body)
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := tpl.(map[string]interface{})
+	resources, _ := root["Resources"].(map[string]interface{})
+
+	return resources, nil
+}
+
+func resourceType(resource interface{}) string {
+	r, _ := resource.(map[string]interface{})
+	t, _ := r["Type"].(string)
+
+	return t
+}
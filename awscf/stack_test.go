@@ -1,15 +1,21 @@
 package awscf
 
 import (
+	"context"
 	"errors"
+	"io/ioutil"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	saAws "github.com/molecule-man/stack-assembly/aws"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -27,7 +33,7 @@ func TestOnlyRequiredParametersAreSubmitted(t *testing.T) {
 		WithParameter("bar", "barval").
 		WithParameter("buz", "buzval")
 
-	_, err := chSet.Register()
+	_, err := chSet.Register(context.Background())
 	require.NoError(t, err)
 
 	expected := []*cloudformation.Parameter{
@@ -55,12 +61,156 @@ func TestChangeSetCreationErrors(t *testing.T) {
 
 		_, err := NewStack("mystack", cf, s3Uploader()).
 			ChangeSet("body").
-			Register()
+			Register(context.Background())
 
 		assert.EqualError(t, err, tc.err.Error())
 	}
 }
 
+func TestInterruptedChangeSetCreationDeletesChangeSet(t *testing.T) {
+	cf := &cfMock{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).
+		ChangeSet("body").
+		Register(ctx)
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{chSet.ID}, cf.deletedChangeSets)
+}
+
+func TestChangeSetIsNotCreatedForStackInFailedState(t *testing.T) {
+	for _, status := range []string{
+		cloudformation.StackStatusRollbackComplete,
+		cloudformation.StackStatusUpdateRollbackFailed,
+		cloudformation.StackStatusUpdateInProgress,
+	} {
+		cf := &cfMock{stackStatus: status}
+
+		_, err := NewStack("mystack", cf, s3Uploader()).
+			ChangeSet("body").
+			Register(context.Background())
+
+		assert.Equal(t, &StackStateError{StackName: "mystack", Status: status}, err)
+		assert.Nil(t, cf.createChangeSetInput)
+	}
+}
+
+func TestImportChangeSetIsCreatedWithResourcesToImport(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusCreateComplete}
+	body := `{"Resources": {
+		"Bucket": {"Type": "AWS::S3::Bucket"},
+		"Queue": {"Type": "AWS::SQS::Queue"}
+	}}`
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).
+		ChangeSet(body).
+		WithResourcesToImport(map[string]map[string]string{
+			"Queue":  {"QueueUrl": "https://queue.url"},
+			"Bucket": {"BucketName": "mybucket"},
+		}).
+		Register(context.Background())
+	require.NoError(t, err)
+
+	expected := []*cloudformation.ResourceToImport{{
+		LogicalResourceId:  aws.String("Bucket"),
+		ResourceType:       aws.String("AWS::S3::Bucket"),
+		ResourceIdentifier: aws.StringMap(map[string]string{"BucketName": "mybucket"}),
+	}, {
+		LogicalResourceId:  aws.String("Queue"),
+		ResourceType:       aws.String("AWS::SQS::Queue"),
+		ResourceIdentifier: aws.StringMap(map[string]string{"QueueUrl": "https://queue.url"}),
+	}}
+
+	assert.True(t, chSet.IsImport)
+	require.NotNil(t, cf.createChangeSetInput)
+	assert.Equal(t, cloudformation.ChangeSetTypeImport, aws.StringValue(cf.createChangeSetInput.ChangeSetType))
+	assert.Equal(t, expected, cf.createChangeSetInput.ResourcesToImport)
+}
+
+func TestImportChangeSetLooksUpResourcesInDownloadedTemplate(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusCreateComplete}
+	s3 := &s3APIMock{objects: map[string]string{
+		"mybucket/tpl.json": `{"Resources": {"Bucket": {"Type": "AWS::S3::Bucket"}}}`,
+	}}
+
+	_, err := NewStack("mystack", cf, saAws.NewS3Uploader(s3Mock{}, s3, saAws.S3Settings{})).
+		ChangeSet("").
+		WithTemplateURL("https://mybucket.s3.amazonaws.com/tpl.json").
+		WithResourcesToImport(map[string]map[string]string{
+			"Bucket": {"BucketName": "mybucket"},
+		}).
+		Register(context.Background())
+	require.NoError(t, err)
+
+	expected := []*cloudformation.ResourceToImport{{
+		LogicalResourceId:  aws.String("Bucket"),
+		ResourceType:       aws.String("AWS::S3::Bucket"),
+		ResourceIdentifier: aws.StringMap(map[string]string{"BucketName": "mybucket"}),
+	}}
+
+	require.NotNil(t, cf.createChangeSetInput)
+	assert.Equal(t, "https://mybucket.s3.amazonaws.com/tpl.json", aws.StringValue(cf.createChangeSetInput.TemplateURL))
+	assert.Equal(t, expected, cf.createChangeSetInput.ResourcesToImport)
+}
+
+func TestNamedChangeSetReplacesNamesake(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusCreateComplete}
+
+	_, err := NewStack("mystack", cf, s3Uploader()).
+		ChangeSet("body").
+		WithName("pending").
+		Register(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"pending"}, cf.deletedChangeSets)
+	require.NotNil(t, cf.createChangeSetInput)
+	assert.Equal(t, "pending", aws.StringValue(cf.createChangeSetInput.ChangeSetName))
+}
+
+func TestChangeSetIsExecutedWithPolicyOverride(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusCreateComplete}
+	cf.templateParameters = []*cloudformation.TemplateParameter{{ParameterKey: aws.String("Env")}}
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).
+		ChangeSet("body").
+		WithParameter("Env", "dev").
+		WithTags(map[string]string{"team": "core"}).
+		Register(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, chSet.ExecWithPolicy(context.Background(), `{"Statement": []}`))
+
+	assert.True(t, chSet.Executed)
+	assert.Equal(t, []string{chSet.ID}, cf.deletedChangeSets)
+	require.NotNil(t, cf.updateStackInput)
+	assert.Equal(t, `{"Statement": []}`, aws.StringValue(cf.updateStackInput.StackPolicyDuringUpdateBody))
+	assert.Nil(t, cf.updateStackInput.StackPolicyBody)
+	assert.Equal(t, "body", aws.StringValue(cf.updateStackInput.TemplateBody))
+	assert.Equal(t, cf.createChangeSetInput.Parameters, cf.updateStackInput.Parameters)
+	assert.Equal(t, cf.createChangeSetInput.Tags, cf.updateStackInput.Tags)
+}
+
+func TestLoadedChangeSetCantBeExecutedWithPolicyOverride(t *testing.T) {
+	cf := &cfMock{executionStatus: cloudformation.ExecutionStatusAvailable}
+
+	chSet, err := NewStack("mystack", cf, nil).LoadChangeSet("arn", true)
+	require.NoError(t, err)
+
+	assert.Error(t, chSet.ExecWithPolicy(context.Background(), `{"Statement": []}`))
+	assert.Nil(t, cf.updateStackInput)
+}
+
+func TestLoadNamedChangeSetNotFound(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusCreateComplete}
+	cf.changesErr = awserr.New(cloudformation.ErrCodeChangeSetNotFoundException, "not found", nil)
+
+	_, err := NewStack("mystack", cf, s3Uploader()).LoadNamedChangeSet("pending")
+
+	assert.Equal(t, ErrChangeSetNotFound, err)
+}
+
 func TestEventTracking(t *testing.T) {
 	mu := sync.Mutex{}
 	wg := sync.WaitGroup{}
@@ -82,7 +232,7 @@ func TestEventTracking(t *testing.T) {
 	}
 
 	stack := NewStack("mystack", cf, s3Uploader())
-	cs, err := stack.ChangeSet("body").Register()
+	cs, err := stack.ChangeSet("body").Register(context.Background())
 	require.NoError(t, err)
 
 	wg.Add(1)
@@ -103,7 +253,7 @@ func TestEventTracking(t *testing.T) {
 
 	go track(t, stack, captured, stop)
 
-	require.NoError(t, cs.Exec())
+	require.NoError(t, cs.Exec(context.Background()))
 	stop <- true
 
 	capturedEvents := []StackEvent{}
@@ -117,6 +267,94 @@ func TestEventTracking(t *testing.T) {
 	assert.Equal(t, expected, capturedEvents)
 }
 
+func TestLoadChangeSet(t *testing.T) {
+	cf := &cfMock{
+		executionStatus: cloudformation.ExecutionStatusAvailable,
+		changeSetParameters: []*cloudformation.Parameter{
+			{ParameterKey: aws.String("foo"), ParameterValue: aws.String("fooval")},
+		},
+	}
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).LoadChangeSet("chset-id", true)
+	require.NoError(t, err)
+
+	assert.Equal(t, "chset-id", chSet.ID)
+	assert.True(t, chSet.IsUpdate)
+	assert.Equal(t, []KeyVal{{Key: "foo", Val: "fooval"}}, chSet.Parameters)
+
+	cf.executionStatus = cloudformation.ExecutionStatusObsolete
+
+	_, err = NewStack("mystack", cf, s3Uploader()).LoadChangeSet("chset-id", true)
+	assert.EqualError(t, err, "change set chset-id can't be executed. Execution status: OBSOLETE")
+}
+
+func TestRegisteredChangeSet(t *testing.T) {
+	cf := &cfMock{
+		body:            "body",
+		executionStatus: cloudformation.ExecutionStatusAvailable,
+		changeSetParameters: []*cloudformation.Parameter{
+			{ParameterKey: aws.String("foo"), ParameterValue: aws.String("fooval")},
+		},
+		changeSetTags: []*cloudformation.Tag{{Key: aws.String("team"), Value: aws.String("core")}},
+	}
+
+	stack := NewStack("mystack", cf, s3Uploader())
+
+	chSet, err := stack.LoadChangeSet("chset-id", true)
+	require.NoError(t, err)
+
+	registered, err := stack.RegisteredChangeSet(chSet)
+	require.NoError(t, err)
+
+	assert.Equal(t, "chset-id", aws.StringValue(cf.getTemplateInput.ChangeSetName))
+	assert.Equal(t, "body", registered.body)
+	assert.Equal(t, map[string]string{"foo": "fooval"}, registered.parameters)
+	assert.Equal(t, map[string]string{"team": "core"}, registered.tags)
+}
+
+func TestChangeDetailsAreLoaded(t *testing.T) {
+	cf := &cfMock{
+		executionStatus: cloudformation.ExecutionStatusAvailable,
+		changeSetChanges: []*cloudformation.Change{{ResourceChange: &cloudformation.ResourceChange{
+			Action:            aws.String(cloudformation.ChangeActionModify),
+			ResourceType:      aws.String("AWS::S3::Bucket"),
+			LogicalResourceId: aws.String("Bucket"),
+			Replacement:       aws.String(cloudformation.ReplacementConditional),
+			Scope:             aws.StringSlice([]string{"Properties"}),
+			Details: []*cloudformation.ResourceChangeDetail{{
+				Target: &cloudformation.ResourceTargetDefinition{
+					Attribute:          aws.String("Properties"),
+					Name:               aws.String("BucketName"),
+					RequiresRecreation: aws.String(cloudformation.RequiresRecreationAlways),
+				},
+				Evaluation:    aws.String(cloudformation.EvaluationTypeStatic),
+				ChangeSource:  aws.String(cloudformation.ChangeSourceParameterReference),
+				CausingEntity: aws.String("Env"),
+			}},
+		}}},
+	}
+
+	chSet, err := NewStack("mystack", cf, s3Uploader()).LoadChangeSet("chset-id", true)
+	require.NoError(t, err)
+
+	expected := []Change{{
+		Action:            "Modify",
+		ResourceType:      "AWS::S3::Bucket",
+		LogicalResourceID: "Bucket",
+		Replacement:       "Conditional",
+		Scope:             []string{"Properties"},
+		Details: []ChangeDetail{{
+			Attribute:          "Properties",
+			Name:               "BucketName",
+			RequiresRecreation: "Always",
+			Evaluation:         "Static",
+			ChangeSource:       "ParameterReference",
+			CausingEntity:      "Env",
+		}},
+	}}
+	assert.Equal(t, expected, chSet.Changes)
+}
+
 func track(t *testing.T, stack *Stack, eventsCh chan<- StackEvent, cancel <-chan bool) {
 	for {
 		events, err := stack.EventsTrack().FreshEvents()
@@ -152,8 +390,22 @@ type cfMock struct {
 
 	body string
 
+	executionStatus     string
+	changeSetParameters []*cloudformation.Parameter
+	changeSetChanges    []*cloudformation.Change
+	changeSetTags       []*cloudformation.Tag
+	getTemplateInput    *cloudformation.GetTemplateInput
+
 	waitStackFunc           func() error
 	describeStackEventsFunc func() (*cloudformation.DescribeStackEventsOutput, error)
+	stackEvents             map[string][]*cloudformation.StackEvent
+	stackResources          []*cloudformation.StackResource
+	stackPolicy             *string
+	outputs                 []*cloudformation.Output
+	imports                 map[string][]string
+	deletedChangeSets       []string
+	stackStatus             string
+	updateStackInput        *cloudformation.UpdateStackInput
 }
 
 func (cf *cfMock) ValidateTemplate(*cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error) {
@@ -165,12 +417,13 @@ func (cf *cfMock) ValidateTemplate(*cloudformation.ValidateTemplateInput) (*clou
 
 func (cf *cfMock) DescribeStacks(*cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
 	out := cloudformation.DescribeStacksOutput{
-		Stacks: []*cloudformation.Stack{{}},
+		Stacks: []*cloudformation.Stack{{StackStatus: aws.String(cf.stackStatus), Outputs: cf.outputs}},
 	}
 
 	return &out, cf.describeErr
 }
-func (cf *cfMock) GetTemplate(*cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+func (cf *cfMock) GetTemplate(inp *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+	cf.getTemplateInput = inp
 	out := cloudformation.GetTemplateOutput{}
 	out.TemplateBody = aws.String(cf.body)
 
@@ -180,6 +433,10 @@ func (cf *cfMock) GetTemplate(*cloudformation.GetTemplateInput) (*cloudformation
 func (cf *cfMock) DescribeChangeSet(*cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error) {
 	out := cloudformation.DescribeChangeSetOutput{}
 	out.Status = aws.String("")
+	out.ExecutionStatus = aws.String(cf.executionStatus)
+	out.Parameters = cf.changeSetParameters
+	out.Changes = cf.changeSetChanges
+	out.Tags = cf.changeSetTags
 
 	return &out, cf.changesErr
 }
@@ -188,25 +445,72 @@ func (cf *cfMock) DescribeStackEvents(input *cloudformation.DescribeStackEventsI
 		return cf.describeStackEventsFunc()
 	}
 
+	if cf.stackEvents != nil {
+		return &cloudformation.DescribeStackEventsOutput{StackEvents: cf.stackEvents[*input.StackName]}, nil
+	}
+
 	return &cloudformation.DescribeStackEventsOutput{}, cf.err
 }
 
-func (cf *cfMock) CreateChangeSet(inp *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+func (cf *cfMock) DescribeStackResources(
+	*cloudformation.DescribeStackResourcesInput,
+) (*cloudformation.DescribeStackResourcesOutput, error) {
+	return &cloudformation.DescribeStackResourcesOutput{StackResources: cf.stackResources}, cf.err
+}
+
+func (cf *cfMock) ListImports(inp *cloudformation.ListImportsInput) (*cloudformation.ListImportsOutput, error) {
+	imports, ok := cf.imports[*inp.ExportName]
+	if !ok {
+		return nil, awserr.New("ValidationError", "Export '"+*inp.ExportName+"' is not imported by any stack.", nil)
+	}
+
+	return &cloudformation.ListImportsOutput{Imports: aws.StringSlice(imports)}, nil
+}
+
+func (cf *cfMock) GetStackPolicy(*cloudformation.GetStackPolicyInput) (*cloudformation.GetStackPolicyOutput, error) {
+	return &cloudformation.GetStackPolicyOutput{StackPolicyBody: cf.stackPolicy}, cf.err
+}
+
+func (cf *cfMock) SetStackPolicy(inp *cloudformation.SetStackPolicyInput) (*cloudformation.SetStackPolicyOutput, error) {
+	cf.stackPolicy = inp.StackPolicyBody
+	return &cloudformation.SetStackPolicyOutput{}, cf.err
+}
+
+func (cf *cfMock) CreateChangeSetWithContext(
+	_ aws.Context,
+	inp *cloudformation.CreateChangeSetInput,
+	_ ...request.Option,
+) (*cloudformation.CreateChangeSetOutput, error) {
 	cf.createChangeSetInput = inp
 	out := cloudformation.CreateChangeSetOutput{}
 
 	return &out, cf.createErr
 }
+
 func (cf *cfMock) ExecuteChangeSet(inp *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
 	return nil, cf.err
 }
 
 func (cf *cfMock) WaitUntilChangeSetCreateCompleteWithContext(
-	aws.Context,
-	*cloudformation.DescribeChangeSetInput,
-	...request.WaiterOption) error {
+	ctx aws.Context,
+	_ *cloudformation.DescribeChangeSetInput,
+	_ ...request.WaiterOption) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	return cf.waitChSetErr
 }
+
+func (cf *cfMock) DeleteChangeSet(inp *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error) {
+	cf.deletedChangeSets = append(cf.deletedChangeSets, aws.StringValue(inp.ChangeSetName))
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+func (cf *cfMock) UpdateStack(inp *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	cf.updateStackInput = inp
+	return &cloudformation.UpdateStackOutput{}, cf.err
+}
+
 func (cf *cfMock) WaitUntilStackUpdateCompleteWithContext(aws.Context, *cloudformation.DescribeStacksInput, ...request.WaiterOption) error {
 	if cf.waitStackFunc != nil {
 		return cf.waitStackFunc()
@@ -222,3 +526,19 @@ func s3Uploader() *saAws.S3Uploader {
 type s3Mock struct {
 	saAws.S3UploadManager
 }
+
+type s3APIMock struct {
+	s3iface.S3API
+
+	// objects are the contents of the objects keyed by "bucket/key"
+	objects map[string]string
+}
+
+func (m *s3APIMock) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	obj, ok := m.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)]
+	if !ok {
+		return nil, errors.New("object not found")
+	}
+
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(strings.NewReader(obj))}, nil
+}
@@ -0,0 +1,105 @@
+package awscf
+
+import (
+	"fmt"
+	"strings"
+
+	// unlike yaml.v2 (which is used to parse the configs), yaml.v3 keeps the
+	// custom tags of the decoded nodes. yaml.v2 drops the tags of the short
+	// form intrinsic functions (e.g. !Ref) leaving the bare arguments
+	yaml "gopkg.in/yaml.v3"
+)
+
+// ParseTemplate parses cloudformation template written either in json or in
+// yaml. Short form of intrinsic functions (e.g. !Ref, !Sub, !GetAtt) is
+// converted into the full form (e.g. Ref, Fn::Sub, Fn::GetAtt), so that the
+// same template written in json or in yaml is parsed into the same tree.
+func ParseTemplate(body string) (interface{}, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	return convertTplNode(doc.Content[0])
+}
+
+func convertTplNode(n *yaml.Node) (interface{}, error) {
+	var v interface{}
+
+	switch n.Kind {
+	case yaml.AliasNode:
+		return convertTplNode(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			val, err := convertTplNode(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+
+			m[n.Content[i].Value] = val
+		}
+
+		v = m
+	case yaml.SequenceNode:
+		s := make([]interface{}, len(n.Content))
+
+		for i, c := range n.Content {
+			val, err := convertTplNode(c)
+			if err != nil {
+				return nil, err
+			}
+
+			s[i] = val
+		}
+
+		v = s
+	case yaml.ScalarNode:
+		if isIntrinsicTag(n.Tag) {
+			v = n.Value
+			break
+		}
+
+		if err := n.Decode(&v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unexpected yaml node at line %d", n.Line)
+	}
+
+	if isIntrinsicTag(n.Tag) {
+		return intrinsicFunc(n.Tag[1:], v), nil
+	}
+
+	return v, nil
+}
+
+func isIntrinsicTag(tag string) bool {
+	return strings.HasPrefix(tag, "!") && !strings.HasPrefix(tag, "!!")
+}
+
+func intrinsicFunc(name string, arg interface{}) map[string]interface{} {
+	switch name {
+	case "Ref", "Condition":
+		return map[string]interface{}{name: arg}
+	case "GetAtt":
+		if s, ok := arg.(string); ok {
+			parts := strings.SplitN(s, ".", 2)
+			args := make([]interface{}, len(parts))
+
+			for i, p := range parts {
+				args[i] = p
+			}
+
+			arg = args
+		}
+	}
+
+	return map[string]interface{}{"Fn::" + name: arg}
+}
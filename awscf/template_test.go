@@ -0,0 +1,60 @@
+package awscf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYamlAndJSONTemplatesAreParsedIntoSameTree(t *testing.T) {
+	yamlBody := `
+Parameters:
+  Env:
+    Type: String
+    Default: dev
+Resources:
+  Topic:
+    Type: AWS::SNS::Topic
+    Properties:
+      TopicName: !Sub "${AWS::StackName}-${Env}"
+      DisplayName: !Join ["-", [!Ref Env, topic]]
+Outputs:
+  TopicArn:
+    Value: !GetAtt Topic.TopicArn
+    Export:
+      Name: !ImportValue other-export
+`
+	jsonBody := `{
+  "Parameters": {"Env": {"Type": "String", "Default": "dev"}},
+  "Resources": {
+    "Topic": {
+      "Type": "AWS::SNS::Topic",
+      "Properties": {
+        "TopicName": {"Fn::Sub": "${AWS::StackName}-${Env}"},
+        "DisplayName": {"Fn::Join": ["-", [{"Ref": "Env"}, "topic"]]}
+      }
+    }
+  },
+  "Outputs": {
+    "TopicArn": {
+      "Value": {"Fn::GetAtt": ["Topic", "TopicArn"]},
+      "Export": {"Name": {"Fn::ImportValue": "other-export"}}
+    }
+  }
+}`
+
+	fromYaml, err := ParseTemplate(yamlBody)
+	require.NoError(t, err)
+
+	fromJSON, err := ParseTemplate(jsonBody)
+	require.NoError(t, err)
+
+	assert.Equal(t, fromJSON, fromYaml)
+}
+
+func TestParseEmptyTemplate(t *testing.T) {
+	tpl, err := ParseTemplate("")
+	require.NoError(t, err)
+	assert.Nil(t, tpl)
+}
@@ -0,0 +1,150 @@
+package awscf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+)
+
+const (
+	defaultTimeout               = 30 * time.Minute
+	defaultPollInterval          = 2 * time.Second
+	defaultChangeSetTimeout      = 2 * time.Minute
+	defaultChangeSetPollInterval = 1 * time.Second
+
+	// message of the waiter error returned when max attempts are exceeded
+	waiterExceededMsg = "exceeded wait attempts"
+
+	// number of the latest stack events reported when the timeout is hit
+	timeoutEventsNum = 5
+)
+
+// WaitOpts configures how the completion of stack operations is waited for.
+// Zero values mean the defaults.
+type WaitOpts struct {
+	// Timeout is the maximum duration of creation, update or deletion of the
+	// stack.
+	Timeout time.Duration
+
+	// ChangeSetTimeout is the maximum duration of change set creation.
+	ChangeSetTimeout time.Duration
+
+	// PollInterval is the interval between the checks of stack (or change
+	// set) status.
+	PollInterval time.Duration
+}
+
+func (w WaitOpts) stackTimeout() time.Duration {
+	return durationOrDefault(w.Timeout, defaultTimeout)
+}
+
+func (w WaitOpts) changeSetTimeout() time.Duration {
+	return durationOrDefault(w.ChangeSetTimeout, defaultChangeSetTimeout)
+}
+
+func (w WaitOpts) pollInterval() time.Duration {
+	return durationOrDefault(w.PollInterval, defaultPollInterval)
+}
+
+func (w WaitOpts) stackWaiter() request.WaiterOption {
+	return waiterOption(w.stackTimeout(), w.pollInterval())
+}
+
+func (w WaitOpts) changeSetWaiter() request.WaiterOption {
+	return waiterOption(w.changeSetTimeout(), durationOrDefault(w.PollInterval, defaultChangeSetPollInterval))
+}
+
+func waiterOption(timeout, interval time.Duration) request.WaiterOption {
+	attempts := int(timeout / interval)
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	return func(w *request.Waiter) {
+		w.MaxAttempts = attempts
+		w.Delay = request.ConstantWaiterDelay(interval)
+	}
+}
+
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+
+	return d
+}
+
+// isTimeout tells whether the waiter gave up because it ran out of attempts.
+func isTimeout(err error) bool {
+	aerr, ok := err.(awserr.Error)
+
+	return ok && aerr.Code() == request.WaiterResourceNotReadyErrorCode && aerr.Message() == waiterExceededMsg
+}
+
+// TimeoutError is returned when the stack operation isn't complete within
+// the configured timeout.
+type TimeoutError struct {
+	StackName    string
+	Timeout      time.Duration
+	Status       string
+	StatusReason string
+	// LastEvents are the latest events of the stack. The newest event is the
+	// first one.
+	LastEvents []StackEvent
+}
+
+func (e *TimeoutError) Error() string {
+	msg := fmt.Sprintf("stack %s operation is not complete within %s. Last status: %s", e.StackName, e.Timeout, e.Status)
+
+	if e.StatusReason != "" {
+		msg += fmt.Sprintf(" (%s)", e.StatusReason)
+	}
+
+	if len(e.LastEvents) == 0 {
+		return msg
+	}
+
+	lines := []string{msg + ". Last events:"}
+
+	for _, ev := range e.LastEvents {
+		line := fmt.Sprintf("  %s %s %s %s", ev.Timestamp.Format(time.RFC3339), ev.Status, ev.ResourceType, ev.LogicalResourceID)
+		if ev.StatusReason != "" {
+			line += " " + ev.StatusReason
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// timeoutError converts the error of the waiter into TimeoutError if the
+// waiter timed out. Otherwise the error is returned as is.
+func timeoutError(err error, cf cloudformationiface.CloudFormationAPI, stackName string, timeout time.Duration) error {
+	if !isTimeout(err) {
+		return err
+	}
+
+	terr := &TimeoutError{StackName: stackName, Timeout: timeout}
+	stack := NewStack(stackName, cf, nil)
+
+	// the timeout is reported even if the details can't be retrieved
+	if info, ierr := stack.Info(); ierr == nil {
+		terr.Status = info.Status()
+		terr.StatusReason = info.StatusDescription()
+	}
+
+	if events, eerr := stack.Events(); eerr == nil {
+		if len(events) > timeoutEventsNum {
+			events = events[:timeoutEventsNum]
+		}
+
+		terr.LastEvents = events
+	}
+
+	return terr
+}
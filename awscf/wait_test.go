@@ -0,0 +1,83 @@
+package awscf
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeoutErrorReportsLastEvents(t *testing.T) {
+	events := []*cloudformation.StackEvent{}
+	for _, id := range []string{"7", "6", "5", "4", "3", "2", "1"} {
+		events = append(events, &cloudformation.StackEvent{EventId: aws.String(id)})
+	}
+
+	cf := &cfMock{
+		waitStackFunc: func() error {
+			return awserr.New(request.WaiterResourceNotReadyErrorCode, "exceeded wait attempts", nil)
+		},
+		describeStackEventsFunc: func() (*cloudformation.DescribeStackEventsOutput, error) {
+			return &cloudformation.DescribeStackEventsOutput{StackEvents: events}, nil
+		},
+	}
+
+	stack := NewStack("mystack", cf, s3Uploader()).WithWaitOpts(WaitOpts{Timeout: time.Minute})
+	chSet, err := stack.LoadChangeSet("chst", true)
+	require.Error(t, err) // execution status is not available in the mock
+
+	err = chSet.Wait(context.Background())
+
+	var terr *TimeoutError
+
+	require.True(t, errors.As(err, &terr))
+	assert.Equal(t, "mystack", terr.StackName)
+	assert.Equal(t, time.Minute, terr.Timeout)
+	require.Len(t, terr.LastEvents, 5)
+	assert.Equal(t, "7", terr.LastEvents[0].ID)
+}
+
+func TestFailedStackOperationIsNotTimeout(t *testing.T) {
+	failure := awserr.New(request.WaiterResourceNotReadyErrorCode,
+		"failed waiting for successful resource state", errors.New("rollback"))
+
+	cf := &cfMock{waitStackFunc: func() error { return failure }}
+	chSet, _ := NewStack("mystack", cf, s3Uploader()).LoadChangeSet("chst", true)
+
+	assert.Equal(t, failure, chSet.Wait(context.Background()))
+}
+
+func TestWaiterOption(t *testing.T) {
+	w := request.Waiter{}
+	WaitOpts{Timeout: time.Minute, PollInterval: 10 * time.Second}.stackWaiter()(&w)
+
+	assert.Equal(t, 6, w.MaxAttempts)
+	assert.Equal(t, 10*time.Second, w.Delay(1))
+
+	WaitOpts{}.changeSetWaiter()(&w)
+
+	assert.Equal(t, 120, w.MaxAttempts)
+	assert.Equal(t, time.Second, w.Delay(1))
+}
+
+func TestWaitUntilIdle(t *testing.T) {
+	cf := &cfMock{stackStatus: cloudformation.StackStatusUpdateComplete}
+	stack := NewStack("mystack", cf, s3Uploader())
+
+	assert.NoError(t, stack.WaitUntilIdle(context.Background()))
+
+	cf.stackStatus = cloudformation.StackStatusUpdateInProgress
+	stack.WithWaitOpts(WaitOpts{Timeout: time.Millisecond, PollInterval: time.Millisecond})
+
+	var terr *TimeoutError
+
+	require.True(t, errors.As(stack.WaitUntilIdle(context.Background()), &terr))
+	assert.Equal(t, cloudformation.StackStatusUpdateInProgress, terr.Status)
+}
@@ -18,6 +18,10 @@ type CLI struct {
 	Errorer io.Writer
 
 	Color Color
+
+	// Terminal tells whether Writer is an interactive terminal, i.e. the
+	// output can be redrawn in place.
+	Terminal bool
 }
 
 func (cli CLI) Print(msg string) {
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// LineWriter prints the written output line by line through the logger, so
+// that every line gets the prefix of the logger. The incomplete last line is
+// printed by Flush.
+type LineWriter struct {
+	mu     sync.Mutex
+	logger *Logger
+	buf    bytes.Buffer
+}
+
+// LineWriter returns the writer printing through the logger.
+func (l *Logger) LineWriter() *LineWriter {
+	return &LineWriter{logger: l}
+}
+
+func (lw *LineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	lw.buf.Write(p)
+
+	for {
+		i := bytes.IndexByte(lw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+
+		line := lw.buf.Next(i + 1)
+		lw.logger.Print(strings.TrimRight(string(line), "\r\n"))
+	}
+
+	return len(p), nil
+}
+
+// Flush prints the incomplete last line.
+func (lw *LineWriter) Flush() {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	if lw.buf.Len() > 0 {
+		lw.logger.Print(lw.buf.String())
+		lw.buf.Reset()
+	}
+}
@@ -0,0 +1,22 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriterPrefixesEveryLine(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := CLI{Writer: buf}.PrefixedLogger("[stack] ").LineWriter()
+
+	_, _ = w.Write([]byte("line1\nli"))
+	_, _ = w.Write([]byte("ne2\r\nline3"))
+
+	assert.Equal(t, "[stack] line1\n[stack] line2\n", buf.String())
+
+	w.Flush()
+
+	assert.Equal(t, "[stack] line1\n[stack] line2\n[stack] line3\n", buf.String())
+}
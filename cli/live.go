@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LiveBlock is a block of lines that is redrawn in place. It's meant to be
+// used only when the output is a terminal.
+type LiveBlock struct {
+	w     io.Writer
+	lines int
+}
+
+func NewLiveBlock(w io.Writer) *LiveBlock {
+	return &LiveBlock{w: w}
+}
+
+// Update replaces the previously drawn content of the block with the new one.
+func (b *LiveBlock) Update(content string) error {
+	content = strings.TrimSuffix(content, "\n")
+
+	prefix := ""
+	if b.lines > 0 {
+		// move the cursor to the first line of the block and clear everything
+		// below it
+		prefix = fmt.Sprintf("\x1b[%dA\r\x1b[J", b.lines)
+	}
+
+	b.lines = strings.Count(content, "\n") + 1
+
+	_, err := io.WriteString(b.w, prefix+content+"\n")
+
+	return err
+}
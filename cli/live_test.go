@@ -0,0 +1,20 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveBlockRedrawsPreviousContent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	b := NewLiveBlock(buf)
+
+	require.NoError(t, b.Update("line1\nline2\n"))
+	require.NoError(t, b.Update("line3"))
+	require.NoError(t, b.Update("line4"))
+
+	assert.Equal(t, "line1\nline2\n\x1b[2A\r\x1b[Jline3\n\x1b[1A\r\x1b[Jline4\n", buf.String())
+}
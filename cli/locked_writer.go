@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"io"
+	"sync"
+)
+
+// LockedWriter serializes writes to the underlying writer. It is meant to be
+// used when several goroutines print into the same output, so that every
+// printed line stays intact.
+type LockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLockedWriter(w io.Writer) *LockedWriter {
+	return &LockedWriter{w: w}
+}
+
+func (lw *LockedWriter) Write(buf []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	return lw.w.Write(buf)
+}
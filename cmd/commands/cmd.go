@@ -1,14 +1,19 @@
 package commands
 
 import (
+	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/BurntSushi/toml"
 	assembly "github.com/molecule-man/stack-assembly"
+	"github.com/molecule-man/stack-assembly/awscf"
 	"github.com/molecule-man/stack-assembly/cli"
 	"github.com/molecule-man/stack-assembly/conf"
 	"github.com/spf13/cobra"
@@ -29,8 +34,21 @@ type Commands struct {
 	}
 	NonInteractive *bool
 
+	// Ctx is canceled when the user interrupts the command
+	Ctx context.Context
+
 	cfg      *conf.Config
 	origArgs []string
+
+	// waitFlags take precedence over the timeouts and the poll interval
+	// configured in the config files
+	waitFlags *waitFlags
+}
+
+type waitFlags struct {
+	timeout          string
+	changeSetTimeout string
+	pollInterval     string
 }
 
 func (c *Commands) RootCmd() *cobra.Command {
@@ -39,9 +57,15 @@ func (c *Commands) RootCmd() *cobra.Command {
 		c.NonInteractive = &nonInteractive
 	}
 
+	if c.Ctx == nil {
+		c.Ctx = context.Background()
+	}
+
 	out := "text"
 	c.AWSCommandsCfg.output = &out
 
+	c.waitFlags = &waitFlags{}
+
 	c.cfg = &conf.Config{
 		Parameters:   map[string]string{},
 		Tags:         map[string]string{},
@@ -62,6 +86,13 @@ func (c *Commands) RootCmd() *cobra.Command {
 	rootCmd.PersistentFlags().StringVarP(&c.cfg.Settings.Aws.Region, "region", "r", os.Getenv("AWS_REGION"), "AWS region")
 	rootCmd.PersistentFlags().StringVar(&c.cfg.Settings.Aws.Endpoint, "endpoint-url", "", "AWS endpoint url")
 
+	rootCmd.PersistentFlags().StringVar(&c.waitFlags.timeout, "timeout", "", flagDescription(
+		"Maximum duration of stack creation, update or deletion (e.g. 45m). Default: 30m"))
+	rootCmd.PersistentFlags().StringVar(&c.waitFlags.changeSetTimeout, "change-set-timeout", "", flagDescription(
+		"Maximum duration of change set creation (e.g. 5m). Default: 2m"))
+	rootCmd.PersistentFlags().StringVar(&c.waitFlags.pollInterval, "poll-interval", "", flagDescription(
+		"Interval between the checks of stack status and events (e.g. 10s). Default: 2s"))
+
 	rootCmd.PersistentFlags().BoolVar(&c.Cli.Color.Disabled, "nocolor", false,
 		"Disables color output")
 	rootCmd.PersistentFlags().BoolVarP(c.NonInteractive, "no-interaction", "n", *c.NonInteractive,
@@ -73,8 +104,14 @@ func (c *Commands) RootCmd() *cobra.Command {
 	rootCmd.AddCommand(
 		c.infoCmd(),
 		c.syncCmd(),
+		c.importCmd(),
+		c.unblockCmd(),
+		c.executeChangeSetsCmd(),
+		c.driftCmd(),
 		c.deployCmd(),
 		c.diffCmd(),
+		c.planCmd(),
+		c.applyCmd(),
 		c.deleteCmd(),
 		c.dumpConfigCmd(),
 		c.cloudformationCmd(),
@@ -89,7 +126,7 @@ func (c Commands) infoCmd() *cobra.Command {
 		Use:   "info",
 		Short: "Show info about the stacks",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := c.CfgLoader.LoadConfig(cfgFiles, c.cfg); err != nil {
+			if err := c.loadConfig(cfgFiles); err != nil {
 				return err
 			}
 
@@ -103,6 +140,7 @@ func (c Commands) infoCmd() *cobra.Command {
 }
 
 func (c Commands) deployCmd() *cobra.Command {
+	opts := assembly.SyncOpts{}
 	cmd := &cobra.Command{
 		Use:   "deploy <stack name> <template path>",
 		Args:  cobra.ExactArgs(2),
@@ -111,15 +149,19 @@ func (c Commands) deployCmd() *cobra.Command {
 			c.cfg.Name = args[0]
 			c.cfg.Path = args[1]
 
-			if err := c.CfgLoader.InitConfig(c.cfg); err != nil {
+			if err := c.initConfig(); err != nil {
 				return err
 			}
 
-			_, err := c.SA.Sync(*c.cfg, *c.NonInteractive)
+			opts.NonInteractive = *c.NonInteractive
+
+			_, err := c.SA.Sync(c.Ctx, *c.cfg, opts)
 			return err
 		},
 	}
 
+	addNoExecuteFlags(cmd, "no-execute", &opts)
+
 	cmd.Flags().StringSliceVar(&c.cfg.Capabilities, "capabilities", c.cfg.Capabilities,
 		"A list of capabilities that you must specify before AWS\nCloudformation can create certain stacks. E.g. CAPABILITY_IAM")
 	cmd.Flags().StringToStringVar(&c.cfg.Tags, "tags", c.cfg.Tags, "A list of tags to associate with the stack that is deployed")
@@ -128,7 +170,12 @@ func (c Commands) deployCmd() *cobra.Command {
 }
 
 func (c Commands) syncCmd() *cobra.Command {
+	var explainOrder bool
+
+	var overridePolicy string
+
 	cfgFiles := []string{}
+	opts := assembly.SyncOpts{}
 	cmd := &cobra.Command{
 		Use:   "sync [<ID> [<ID> ...]]",
 		Short: "Deploy stacks using the config file(s)",
@@ -160,44 +207,358 @@ have to be specified as well:
   stas sync parent_tpl child_tpl`,
 
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := c.CfgLoader.LoadConfig(cfgFiles, c.cfg); err != nil {
+			if err := c.loadConfig(cfgFiles); err != nil {
 				return err
 			}
 
-			for _, id := range args {
-				stack, ok := c.cfg.Stacks[id]
-				if !ok {
-					foundIds := make([]string, 0, len(c.cfg.Stacks))
-					for id := range c.cfg.Stacks {
-						foundIds = append(foundIds, id)
-					}
+			c.selectStack(args)
+
+			if explainOrder {
+				return c.SA.ExplainOrder(*c.cfg)
+			}
+
+			opts.NonInteractive = *c.NonInteractive
+
+			if opts.JournalPath == "" {
+				opts.JournalPath = defaultJournalPath(cfgFiles, args)
+			}
 
-					assembly.MustSucceed(fmt.Errorf("ID %s is not found in the config. Found IDs: %v", id, foundIds))
+			if overridePolicy != "" {
+				policy, err := c.CfgLoader.LoadStackPolicy(overridePolicy)
+				if err != nil {
+					return err
 				}
 
-				*c.cfg = stack
+				opts.OverridePolicy = policy
 			}
 
-			_, err := c.SA.Sync(*c.cfg, *c.NonInteractive)
+			_, err := c.SA.Sync(c.Ctx, *c.cfg, opts)
 			return err
 		},
 	}
 
+	addConfigFlag(cmd, &cfgFiles)
+	addConcurrencyFlag(cmd, &opts.Concurrency)
+	cmd.Flags().BoolVar(&explainOrder, "explain-order", false, flagDescription(
+		"Print the order in which the stacks are synchronized together with ",
+		"the explicit and inferred dependencies causing it. Nothing is synchronized"))
+	opts.OnBusy = assembly.OnBusyFail
+	cmd.Flags().Var(&EnumFlag{Val: &opts.OnBusy, Enums: []string{assembly.OnBusyFail, assembly.OnBusyWait}},
+		"on-busy", flagDescription(
+			"What to do if another operation is in progress on the stack. ",
+			"Either fail or wait (for the operation to complete)"))
+	cmd.Flags().BoolVar(&opts.KeepGoing, "keep-going", false, flagDescription(
+		"Don't stop on the failed stack. Skip only the stacks depending on ",
+		"it, synchronize the rest and print the summary"))
+	cmd.Flags().BoolVar(&opts.Resume, "resume", false, flagDescription(
+		"Skip the stacks that are synchronized in the previous failed run ",
+		"and are unchanged since then"))
+	cmd.Flags().StringVar(&opts.JournalPath, "journal", "", flagDescription(
+		"Path of the file where the progress of the synchronization is ",
+		"recorded. By default the file is created in the user cache dir and ",
+		"is specific to the working dir, config files and IDs"))
+	addNoExecuteFlags(cmd, "no-execute", &opts)
+	cmd.Flags().BoolVar(&opts.FailOnDrift, "fail-on-drift", false, flagDescription(
+		"Detect the drift of every stack before creating its change set and ",
+		"fail if the stack has drifted from its template"))
+	cmd.Flags().StringVar(&overridePolicy, "override-policy", "", flagDescription(
+		"Stack policy (json, yaml or path to the file containing it) that is ",
+		"in force instead of the policy of the updated stacks while they are ",
+		"updated"))
+
+	return cmd
+}
+
+func (c Commands) driftCmd() *cobra.Command {
+	cfgFiles := []string{}
+	opts := assembly.DriftOpts{Output: assembly.DriftOutputText}
+	cmd := &cobra.Command{
+		Use:   "drift [<ID> [<ID> ...]]",
+		Short: "Detect drift of the stacks",
+		Long: `Detects whether the deployed stacks differ from their templates and shows
+the drift status of every resource together with the property differences.
+The command exits with nonzero status if any of the stacks has drifted. The IDs
+are specified the same way as in the sync command.`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.loadConfig(cfgFiles); err != nil {
+				return err
+			}
+
+			c.selectStack(args)
+
+			drifted, err := c.SA.DetectDrift(c.Ctx, *c.cfg, opts)
+			if err == nil && drifted {
+				return ErrDriftDetected
+			}
+
+			return err
+		},
+	}
+
+	addConfigFlag(cmd, &cfgFiles)
+	cmd.Flags().Var(&EnumFlag{Val: &opts.Output, Enums: []string{assembly.DriftOutputText, assembly.DriftOutputJSON}},
+		"output", "Output format. Either text or json")
+
+	return cmd
+}
+
+func (c Commands) executeChangeSetsCmd() *cobra.Command {
+	cfgFiles := []string{}
+	opts := assembly.SyncOpts{}
+	cmd := &cobra.Command{
+		Use:   "execute-change-sets [<ID> [<ID> ...]]",
+		Short: "Execute the change sets kept by sync --no-execute",
+		Long: `Executes the change sets that are created by running sync (or deploy) with
+--no-execute flag. The change sets are executed in the same order as the stacks
+are synchronized. The stacks having no kept change set are skipped. The IDs are
+specified the same way as in the sync command.`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.loadConfig(cfgFiles); err != nil {
+				return err
+			}
+
+			c.selectStack(args)
+
+			opts.NonInteractive = *c.NonInteractive
+
+			return c.SA.ExecuteChangeSets(c.Ctx, *c.cfg, opts)
+		},
+	}
+
+	addConfigFlag(cmd, &cfgFiles)
+	cmd.Flags().StringVar(&opts.ChangeSetName, "change-set-name", assembly.DefaultChangeSetName,
+		"Name of the change sets to execute")
+
+	return cmd
+}
+
+func (c Commands) importCmd() *cobra.Command {
+	cfgFiles := []string{}
+	opts := assembly.SyncOpts{}
+	cmd := &cobra.Command{
+		Use:   "import <ID> [<ID> ...]",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Import existing resources into the stack",
+		Long: `Imports existing resources into the stack using the import section of
+the stack config. The import section maps logical IDs of the resources to their
+identifiers:
+
+    stacks:
+      tpl1:
+        name: mystack
+        path: path/to/tpl.json
+        import:
+          MyBucket:
+            BucketName: my-existing-bucket
+
+The IDs of the parent stacks have to be specified the same way as in the sync
+command:
+
+  stas import tpl1`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.loadConfig(cfgFiles); err != nil {
+				return err
+			}
+
+			c.selectStack(args)
+
+			opts.NonInteractive = *c.NonInteractive
+
+			return c.SA.Import(c.Ctx, *c.cfg, opts)
+		},
+	}
+
+	addConfigFlag(cmd, &cfgFiles)
+
+	return cmd
+}
+
+func (c Commands) unblockCmd() *cobra.Command {
+	cfgFiles := []string{}
+	cmd := &cobra.Command{
+		Use:   "unblock <ID> [<ID> ...] <resource>",
+		Args:  cobra.MinimumNArgs(2),
+		Short: "Unblock the blocked resource of the stack",
+		Long: `Removes the resource from the deny statements of the stack policy so that
+the resource can be replaced or deleted. The IDs are specified the same way as
+in the sync command. The resource is the logical ID of the resource:
+
+  stas unblock tpl1 DbInstance
+
+Note that the next sync blocks the resource again unless it's removed from the
+blocked resources of the config.`,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.loadConfig(cfgFiles); err != nil {
+				return err
+			}
+
+			c.selectStack(args[:len(args)-1])
+
+			return c.SA.Unblock(*c.cfg, args[len(args)-1])
+		},
+	}
+
 	addConfigFlag(cmd, &cfgFiles)
 
 	return cmd
 }
 
+// selectStack narrows the loaded config down to the stack identified by the
+// path of IDs.
+func (c Commands) selectStack(ids []string) {
+	for _, id := range ids {
+		stack, ok := c.cfg.Stacks[id]
+		if !ok {
+			foundIds := make([]string, 0, len(c.cfg.Stacks))
+			for id := range c.cfg.Stacks {
+				foundIds = append(foundIds, id)
+			}
+
+			assembly.MustSucceed(fmt.Errorf("ID %s is not found in the config. Found IDs: %v", id, foundIds))
+		}
+
+		*c.cfg = stack
+	}
+}
+
+func defaultJournalPath(cfgFiles, ids []string) string {
+	wd, err := os.Getwd()
+	assembly.MustSucceed(err)
+
+	h := sha256.New()
+	fmt.Fprintln(h, wd)
+	fmt.Fprintln(h, strings.Join(cfgFiles, ","))
+	fmt.Fprintln(h, strings.Join(ids, " "))
+
+	// the journal has to survive the failed run (and the reboot) until the
+	// synchronization is resumed, so the cache dir is preferred over the temp
+	// dir that might be cleaned up in the meantime
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "stack-assembly", fmt.Sprintf("sync-%x.json", h.Sum(nil)[:8]))
+}
+
 func (c Commands) diffCmd() *cobra.Command {
+	var detailedExitCode bool
+
 	cfgFiles := []string{}
+	opts := assembly.DiffOpts{Mode: awscf.DiffModeText, Output: assembly.DiffOutputText}
 	cmd := &cobra.Command{
 		Use:   "diff",
 		Short: "Show diff of the stacks to be deployed",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := c.CfgLoader.LoadConfig(cfgFiles, c.cfg); err != nil {
+			if err := c.loadConfig(cfgFiles); err != nil {
+				return err
+			}
+			hasChanges, err := c.SA.Diff(*c.cfg, opts)
+			if err == nil && hasChanges && detailedExitCode {
+				return ErrChangesDetected
+			}
+
+			return err
+		},
+	}
+
+	addConfigFlag(cmd, &cfgFiles)
+	cmd.Flags().Var(&EnumFlag{Val: &opts.Mode, Enums: []string{awscf.DiffModeText, awscf.DiffModeSemantic}},
+		"diff-mode", flagDescription(
+			"How the templates are compared. Either text (line by line) or ",
+			"semantic (the parsed templates are compared and the changed ",
+			"values are reported by their paths)"))
+	cmd.Flags().Var(&EnumFlag{
+		Val:   &opts.Output,
+		Enums: []string{assembly.DiffOutputText, assembly.DiffOutputJSON, assembly.DiffOutputMarkdown},
+	}, "output", flagDescription(
+		"Output format. Either text, json (one object per stack) or markdown ",
+		"(collapsible section per stack, e.g. for pull request comments)"))
+	cmd.Flags().BoolVar(&detailedExitCode, "detailed-exitcode", false, flagDescription(
+		"Exit with 0 if there are no changes, with 2 if there are changes ",
+		"and with 1 on errors"))
+
+	return cmd
+}
+
+func (c Commands) planCmd() *cobra.Command {
+	var out string
+
+	cfgFiles := []string{}
+	opts := assembly.SyncOpts{}
+	cmd := &cobra.Command{
+		Use:   "plan",
+		Short: "Create change sets for the stacks and save them in the plan file",
+		Long: `Creates change sets for all the stacks specified in the config file(s)
+without executing them. IDs of the change sets together with the state of the
+stacks are saved in the plan file. Exactly these change sets are executed by
+the apply command. Hooks are not executed while planning. If planning of any
+stack fails, the change sets created for the other stacks are deleted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.loadConfig(cfgFiles); err != nil {
+				return err
+			}
+
+			opts.NonInteractive = *c.NonInteractive
+
+			plan, err := c.SA.Plan(c.Ctx, *c.cfg, opts)
+			if err != nil {
+				return err
+			}
+
+			buf, err := json.MarshalIndent(plan, "", "  ")
+			if err != nil {
+				return err
+			}
+
+			if err = ioutil.WriteFile(out, buf, 0644); err != nil {
+				return err
+			}
+
+			c.Cli.Printf("Plan of %d change set(s) is saved in %s", len(plan.Stacks), out)
+
+			return nil
+		},
+	}
+
+	addConfigFlag(cmd, &cfgFiles)
+	cmd.Flags().StringVarP(&out, "out", "o", "plan.json", "Path of the plan file")
+	addConcurrencyFlag(cmd, &opts.Concurrency)
+
+	return cmd
+}
+
+func (c Commands) applyCmd() *cobra.Command {
+	cfgFiles := []string{}
+	cmd := &cobra.Command{
+		Use:   "apply <plan file>",
+		Args:  cobra.ExactArgs(1),
+		Short: "Execute change sets saved in the plan file",
+		Long: `Executes change sets saved in the plan file by the plan command. Nothing is
+executed if any of the planned stacks, templates or parameters has changed since
+the plan was made. The changes of every stack are shown before its change set is
+executed and, unless --no-interaction is given, confirmed. The hooks are run the
+same way as by the sync command.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := c.loadConfig(cfgFiles); err != nil {
 				return err
 			}
-			return c.SA.Diff(*c.cfg)
+
+			buf, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			plan := assembly.Plan{}
+			if err = json.Unmarshal(buf, &plan); err != nil {
+				return fmt.Errorf("not able to parse plan file %s: %w", args[0], err)
+			}
+
+			return c.SA.Apply(c.Ctx, *c.cfg, plan, assembly.SyncOpts{NonInteractive: *c.NonInteractive})
 		},
 	}
 
@@ -208,19 +569,26 @@ func (c Commands) diffCmd() *cobra.Command {
 
 func (c Commands) deleteCmd() *cobra.Command {
 	cfgFiles := []string{}
+	opts := assembly.DeleteOpts{}
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Deletes deployed stacks",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := c.CfgLoader.LoadConfig(cfgFiles, c.cfg); err != nil {
+			if err := c.loadConfig(cfgFiles); err != nil {
 				return err
 			}
 
-			return c.SA.Delete(*c.cfg, *c.NonInteractive)
+			opts.NonInteractive = *c.NonInteractive
+
+			return c.SA.Delete(c.Ctx, *c.cfg, opts)
 		},
 	}
 
 	addConfigFlag(cmd, &cfgFiles)
+	addConcurrencyFlag(cmd, &opts.Concurrency)
+	cmd.Flags().BoolVar(&opts.ForceUnprotect, "force-unprotect", false, flagDescription(
+		"Disable the termination protection of the protected stacks and ",
+		"delete them. Otherwise nothing is deleted if any of the stacks is protected"))
 
 	return cmd
 }
@@ -234,7 +602,7 @@ func (c Commands) dumpConfigCmd() *cobra.Command {
 		Use:   "dump-config",
 		Short: "Dump loaded config into stdout",
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			if err := c.CfgLoader.LoadConfig(cfgFiles, c.cfg); err != nil {
+			if err := c.loadConfig(cfgFiles); err != nil {
 				return err
 			}
 
@@ -273,14 +641,18 @@ func (c Commands) cloudformationCmd() *cobra.Command {
 }
 
 func (c Commands) cfDeployCmd() *cobra.Command {
+	opts := assembly.SyncOpts{}
 	cmd := &cobra.Command{
 		Use:   "deploy",
 		Short: "Drop-in replacement of `aws cloudformation deploy` command",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if err := c.CfgLoader.InitConfig(c.cfg); err != nil {
+			if err := c.initConfig(); err != nil {
 				return err
 			}
-			_, err := c.AWSCommandsCfg.SA.Sync(*c.cfg, *c.NonInteractive)
+
+			opts.NonInteractive = *c.NonInteractive
+
+			_, err := c.AWSCommandsCfg.SA.Sync(c.Ctx, *c.cfg, opts)
 			return err
 		},
 	}
@@ -304,7 +676,7 @@ func (c Commands) cfDeployCmd() *cobra.Command {
 
 	cmd.Flags().Bool("fail-on-empty-changeset", false, "This flag is ignored")
 	cmd.Flags().Bool("no-fail-on-empty-changeset", true, "This flag is ignored")
-	cmd.Flags().Bool("no-execute-changeset", false, "This flag is ignored")
+	addNoExecuteFlags(cmd, "no-execute-changeset", &opts)
 	cmd.Flags().Bool("force-upload", false, "This flag is ignored")
 
 	c.cfSharedFlags(cmd)
@@ -392,11 +764,11 @@ func (c Commands) cfCreateUpdateFunc(cmd *cobra.Command) func(*cobra.Command, []
 			c.cfg.Body = ""
 		}
 
-		if err = c.CfgLoader.InitConfig(c.cfg); err != nil {
+		if err = c.initConfig(); err != nil {
 			return err
 		}
 
-		stacks, err := c.AWSCommandsCfg.SA.Sync(*c.cfg, *c.NonInteractive)
+		stacks, err := c.AWSCommandsCfg.SA.Sync(c.Ctx, *c.cfg, assembly.SyncOpts{NonInteractive: *c.NonInteractive})
 		if err != nil {
 			return err
 		}
@@ -490,11 +862,41 @@ func (c Commands) dumpCfg(format string) {
 	}
 }
 
+func (c Commands) loadConfig(cfgFiles []string) error {
+	if err := c.CfgLoader.LoadConfig(cfgFiles, c.cfg); err != nil {
+		return err
+	}
+
+	return c.cfg.OverrideWaitOpts(c.waitFlags.timeout, c.waitFlags.changeSetTimeout, c.waitFlags.pollInterval)
+}
+
+func (c Commands) initConfig() error {
+	if err := c.CfgLoader.InitConfig(c.cfg); err != nil {
+		return err
+	}
+
+	return c.cfg.OverrideWaitOpts(c.waitFlags.timeout, c.waitFlags.changeSetTimeout, c.waitFlags.pollInterval)
+}
+
 func addConfigFlag(cmd *cobra.Command, val *[]string) {
 	cmd.Flags().StringSliceVarP(val, "configs", "c", []string{},
 		"Alternative config file(s). Default: stack-assembly.yaml")
 }
 
+func addNoExecuteFlags(cmd *cobra.Command, name string, opts *assembly.SyncOpts) {
+	cmd.Flags().BoolVar(&opts.NoExecute, name, false, flagDescription(
+		"Create change sets without executing them. The change sets are kept ",
+		"for review and can be executed by execute-change-sets command"))
+	cmd.Flags().StringVar(&opts.ChangeSetName, "change-set-name", assembly.DefaultChangeSetName, flagDescription(
+		"Name of the change sets kept by --", name, " flag"))
+}
+
+func addConcurrencyFlag(cmd *cobra.Command, val *int) {
+	cmd.Flags().IntVar(val, "concurrency", 1, flagDescription(
+		"Maximum number of stacks processed at the same time. ",
+		"Only the stacks that don't depend on each other are processed concurrently"))
+}
+
 func flagDescription(text ...string) string {
 	return cli.WordWrap(wrapFlagLen, text...)
 }
@@ -519,3 +921,11 @@ func (f *EnumFlag) String() string { return *f.Val }
 
 var ErrNotRunnable = errors.New("command is not runnable")
 var ErrInvalidInput = errors.New("invalid input")
+
+// ErrChangesDetected is returned by the diff command in detailed exit code
+// mode if any of the stacks has changes.
+var ErrChangesDetected = errors.New("changes detected")
+
+// ErrDriftDetected is returned by the drift command if any of the stacks has
+// drifted.
+var ErrDriftDetected = errors.New("drift detected")
@@ -2,9 +2,12 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
 	"github.com/mattn/go-isatty"
 	assembly "github.com/molecule-man/stack-assembly"
@@ -16,11 +19,17 @@ import (
 
 func main() {
 	console := &cli.CLI{
-		Reader:  os.Stdin,
-		Writer:  os.Stdout,
-		Errorer: os.Stderr,
+		Reader:   os.Stdin,
+		Writer:   os.Stdout,
+		Errorer:  os.Stderr,
+		Terminal: isatty.IsTerminal(os.Stdout.Fd()),
 	}
 
+	ctx, interrupt := assembly.WithInterruption(context.Background())
+	defer interrupt(false)
+
+	go handleInterrupts(console, interrupt)
+
 	nonInteractive := !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd())
 
 	cmd := commands.Commands{
@@ -31,6 +40,7 @@ func main() {
 			&aws.Provider{},
 		),
 		NonInteractive: &nonInteractive,
+		Ctx:            ctx,
 	}
 
 	cmd.AWSCommandsCfg.SA = assembly.New(&cli.CLI{
@@ -45,6 +55,8 @@ func main() {
 
 	if err != nil {
 		switch {
+		case errors.Is(err, commands.ErrChangesDetected), errors.Is(err, commands.ErrDriftDetected):
+			os.Exit(2)
 		case errors.Is(err, commands.ErrNotRunnable), strings.HasPrefix(err.Error(), "unknown command"):
 			if os.Getenv("STAS_SUPPRESS_CMD_NOT_FOUND_ERROR") != "yes" {
 				console.Error(err.Error())
@@ -71,3 +83,18 @@ func main() {
 
 	assembly.MustSucceed(err)
 }
+
+// handleInterrupts cancels the context on the first interrupt so that the
+// running operations can be stopped gracefully. The second interrupt
+// terminates the process right away.
+func handleInterrupts(console *cli.CLI, interrupt func(terminate bool)) {
+	interrupts := make(chan os.Signal, 2)
+	signal.Notify(interrupts, os.Interrupt, syscall.SIGTERM)
+
+	sig := <-interrupts
+	console.Warn("Interrupted. Stopping gracefully. Press Ctrl-C again to exit immediately")
+	interrupt(sig == syscall.SIGTERM)
+
+	<-interrupts
+	os.Exit(130)
+}
@@ -1,12 +1,15 @@
 package conf
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
@@ -32,18 +35,62 @@ type Config struct {
 	Tags       map[string]string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	DependsOn  []string          `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	Blocked    []string          `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
-	Hooks      struct {
+
+	// StackPolicy is the stack policy written in json or yaml or the path
+	// to the file containing it. The blocked resources are merged into the
+	// policy
+	StackPolicy string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	Hooks struct {
 		Pre        HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 		Post       HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 		PreCreate  HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 		PostCreate HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 		PreUpdate  HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 		PostUpdate HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+		// PreDelete and PostDelete run around the deletion of the stack.
+		// PreDeleteAll and PostDeleteAll run before and after the deletion
+		// of the stack together with its nested stacks
+		PreDelete     HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+		PostDelete    HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+		PreDeleteAll  HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+		PostDeleteAll HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+		// OnFailure runs when the execution of the change set of the stack
+		// fails. OnRollback runs after it if the stack has been rolled back
+		OnFailure  HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+		OnRollback HookCmds `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	} `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 
 	RollbackConfiguration *cloudformation.RollbackConfiguration `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	UsePreviousTemplate   bool                                  `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 
+	// Import maps logical IDs of the resources to be imported into the stack
+	// to the identifiers of the existing resources
+	Import map[string]map[string]string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// EnableTerminationProtection is reconciled on every synchronization if
+	// it's set. The protection of the stack isn't managed if it's nil
+	EnableTerminationProtection *bool `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// CreationWaitTimeoutInMinutes, OnFailure and DisableRollback are applied
+	// only when the stack is created. CreationWaitTimeoutInMinutes limits only
+	// the wait, CloudFormation keeps creating the stack after it times out.
+	// OnFailure is either ROLLBACK, DELETE or DO_NOTHING
+	CreationWaitTimeoutInMinutes int64  `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+	OnFailure                    string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+	DisableRollback              bool   `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// IncludeNestedStacks makes the change sets include the changes of the
+	// nested stacks (AWS::CloudFormation::Stack resources)
+	IncludeNestedStacks bool `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// RecreateOnRollbackComplete allows to delete the stack that is in
+	// ROLLBACK_COMPLETE state (i.e. the stack which creation failed) in order
+	// to create it again
+	RecreateOnRollbackComplete bool `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
 	RoleARN          string         `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	ClientToken      string         `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	NotificationARNs []string       `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
@@ -51,20 +98,36 @@ type Config struct {
 	ResourceTypes    []string       `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 	Settings         settingsConfig `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 
+	// Timeout, ChangeSetTimeout and PollInterval are durations like "30m"
+	// or "5s". They are inherited by the nested stacks.
+	Timeout          string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+	ChangeSetTimeout string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+	PollInterval     string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
 	Stacks map[string]Config `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 
 	aws AwsProv
+
+	// data used to render the templates of the config
+	tplData tplData
+
+	// parameters referring to outputs of sibling stacks. The inherited
+	// references are resolved among the siblings of the config declaring
+	// them
+	deferredParams []string
+	siblings       map[string]Config
+	inheritedRefs  map[string]outputRef
+
+	// values exported and imported by the templates of the stack and its
+	// nested stacks
+	exports []tplValue
+	imports []tplValue
 }
 
 func (cfg Config) StackConfigsSortedByExecOrder() ([]Config, error) {
 	stackCfgs := make([]Config, len(cfg.Stacks))
-	dg := depgraph.DepGraph{}
 
-	for id, stackCfg := range cfg.Stacks {
-		dg.Add(id, stackCfg.DependsOn)
-	}
-
-	orderedIds, err := dg.Resolve()
+	orderedIds, err := cfg.StackIDsSortedByExecOrder()
 	if err != nil {
 		return stackCfgs, err
 	}
@@ -76,6 +139,78 @@ func (cfg Config) StackConfigsSortedByExecOrder() ([]Config, error) {
 	return stackCfgs, nil
 }
 
+// StackIDsSortedByExecOrder returns IDs of nested stack configs in the order
+// in which the stacks are to be synchronized.
+func (cfg Config) StackIDsSortedByExecOrder() ([]string, error) {
+	dg := cfg.depGraph()
+	return dg.Resolve()
+}
+
+// StackConfigsByExecLevel groups nested stack configs into levels. Stacks of
+// one level don't depend on each other and can be synchronized concurrently.
+// Every level depends only on the levels preceding it.
+func (cfg Config) StackConfigsByExecLevel() ([][]Config, error) {
+	levels, err := cfg.StackIDsByExecLevel()
+	if err != nil {
+		return [][]Config{}, err
+	}
+
+	stackCfgs := make([][]Config, len(levels))
+
+	for i, ids := range levels {
+		stackCfgs[i] = make([]Config, len(ids))
+
+		for j, id := range ids {
+			stackCfgs[i][j] = cfg.Stacks[id]
+		}
+	}
+
+	return stackCfgs, nil
+}
+
+// StackIDsByExecLevel is the same as StackConfigsByExecLevel except that IDs
+// of stack configs are returned.
+func (cfg Config) StackIDsByExecLevel() ([][]string, error) {
+	dg := cfg.depGraph()
+	return dg.Levels()
+}
+
+// DependentStackIDs returns IDs of the nested stacks that depend on the
+// nested stack with the given ID either directly or transitively.
+func (cfg Config) DependentStackIDs(id string) []string {
+	dg := cfg.depGraph()
+	return dg.Dependents(id)
+}
+
+// Hash returns a hash of the stack configuration. Configurations of nested
+// stacks don't affect the hash.
+func (cfg Config) Hash() (string, error) {
+	cfg.Stacks = nil
+
+	buf, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.Sum256(buf)
+
+	return hex.EncodeToString(h[:]), nil
+}
+
+func (cfg Config) depGraph() *depgraph.DepGraph {
+	dg := &depgraph.DepGraph{}
+
+	for id := range cfg.Stacks {
+		dg.Add(id, []string{})
+	}
+
+	for _, dep := range cfg.Dependencies() {
+		dg.Add(dep.ID, []string{dep.DependsOn})
+	}
+
+	return dg
+}
+
 func (cfg Config) ChangeSets() ([]*awscf.ChangeSet, error) {
 	chSets := make([]*awscf.ChangeSet, len(cfg.Stacks))
 
@@ -94,11 +229,44 @@ func (cfg Config) ChangeSets() ([]*awscf.ChangeSet, error) {
 func (cfg Config) Stack() *awscf.Stack {
 	prov := cfg.aws.Must(cfg.Settings.Aws)
 
+	// the wait options are validated when the config is initialized
+	waitOpts, _ := cfg.WaitOpts()
+
 	return awscf.NewStack(
 		cfg.Name,
 		prov.CF,
 		aws.NewS3Uploader(prov.S3UploadManager, prov.S3, cfg.Settings.S3Settings),
-	)
+	).WithWaitOpts(waitOpts)
+}
+
+// WaitOpts parses the timeouts and the poll interval of the stack.
+func (cfg Config) WaitOpts() (awscf.WaitOpts, error) {
+	opts := awscf.WaitOpts{}
+
+	durations := []struct {
+		name  string
+		value string
+		dest  *time.Duration
+	}{
+		{"timeout", cfg.Timeout, &opts.Timeout},
+		{"changeSetTimeout", cfg.ChangeSetTimeout, &opts.ChangeSetTimeout},
+		{"pollInterval", cfg.PollInterval, &opts.PollInterval},
+	}
+
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid %s: %w", d.name, err)
+		}
+
+		*d.dest = parsed
+	}
+
+	return opts, nil
 }
 
 func (cfg Config) ChangeSet() *awscf.ChangeSet {
@@ -113,7 +281,30 @@ func (cfg Config) ChangeSet() *awscf.ChangeSet {
 		WithClientToken(cfg.ClientToken).
 		WithNotificationARNs(cfg.NotificationARNs).
 		WithUsePrevTpl(cfg.UsePreviousTemplate).
-		WithResourceTypes(cfg.ResourceTypes)
+		WithResourceTypes(cfg.ResourceTypes).
+		WithCreationOptions(awscf.CreationOptions{
+			WaitTimeoutInMinutes: cfg.CreationWaitTimeoutInMinutes,
+			OnFailure:            cfg.OnFailure,
+			DisableRollback:      cfg.DisableRollback,
+		}).
+		WithNestedStacks(cfg.IncludeNestedStacks)
+}
+
+// Policy returns the stack policy protecting the blocked resources. Empty
+// string is returned if neither the policy nor the blocked resources are
+// configured.
+func (cfg Config) Policy() (string, error) {
+	if cfg.StackPolicy == "" && len(cfg.Blocked) == 0 {
+		return "", nil
+	}
+
+	return awscf.BlockingPolicy(cfg.StackPolicy, cfg.Blocked)
+}
+
+// ImportChangeSet creates the change set that imports the resources listed
+// in the import section of the config into the stack.
+func (cfg Config) ImportChangeSet() *awscf.ChangeSet {
+	return cfg.ChangeSet().WithResourcesToImport(cfg.Import)
 }
 
 func (cfg *Config) initAwsSettings() {
@@ -129,6 +320,95 @@ func (cfg *Config) initAwsSettings() {
 	}
 }
 
+func (cfg *Config) initWaitOpts(id string) error {
+	if _, err := cfg.WaitOpts(); err != nil {
+		return fmt.Errorf("not possible to parse config for stack %s: %w", id, err)
+	}
+
+	for i, s := range cfg.Stacks {
+		if s.Timeout == "" {
+			s.Timeout = cfg.Timeout
+		}
+
+		if s.ChangeSetTimeout == "" {
+			s.ChangeSetTimeout = cfg.ChangeSetTimeout
+		}
+
+		if s.PollInterval == "" {
+			s.PollInterval = cfg.PollInterval
+		}
+
+		if err := s.initWaitOpts(i); err != nil {
+			return err
+		}
+
+		cfg.Stacks[i] = s
+	}
+
+	return nil
+}
+
+// OverrideWaitOpts sets the timeouts and the poll interval of the stack and
+// all its nested stacks. The empty values are ignored. It is used to give the
+// values provided on the command line precedence over the configured ones.
+func (cfg *Config) OverrideWaitOpts(timeout, changeSetTimeout, pollInterval string) error {
+	override := Config{Timeout: timeout, ChangeSetTimeout: changeSetTimeout, PollInterval: pollInterval}
+
+	if _, err := override.WaitOpts(); err != nil {
+		return err
+	}
+
+	cfg.overrideWaitOpts(override)
+
+	return nil
+}
+
+func (cfg *Config) overrideWaitOpts(override Config) {
+	if override.Timeout != "" {
+		cfg.Timeout = override.Timeout
+	}
+
+	if override.ChangeSetTimeout != "" {
+		cfg.ChangeSetTimeout = override.ChangeSetTimeout
+	}
+
+	if override.PollInterval != "" {
+		cfg.PollInterval = override.PollInterval
+	}
+
+	for i, s := range cfg.Stacks {
+		s.overrideWaitOpts(override)
+		cfg.Stacks[i] = s
+	}
+}
+
+func (cfg Config) validateCreationOpts(id string) error {
+	switch cfg.OnFailure {
+	case "", cloudformation.OnFailureRollback, cloudformation.OnFailureDelete, cloudformation.OnFailureDoNothing:
+	default:
+		return fmt.Errorf("not possible to parse config for stack %s: "+
+			"onFailure should be one of %s, %s or %s", id,
+			cloudformation.OnFailureRollback, cloudformation.OnFailureDelete, cloudformation.OnFailureDoNothing)
+	}
+
+	if cfg.DisableRollback && cfg.OnFailure != "" {
+		return fmt.Errorf("not possible to parse config for stack %s: "+
+			"onFailure and disableRollback can't be used together", id)
+	}
+
+	if cfg.CreationWaitTimeoutInMinutes < 0 {
+		return fmt.Errorf("not possible to parse config for stack %s: creationWaitTimeoutInMinutes can't be negative", id)
+	}
+
+	for i, s := range cfg.Stacks {
+		if err := s.validateCreationOpts(i); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type AwsProv interface {
 	Must(cfg aws.Config) *aws.AWS
 	New(cfg aws.Config) (*aws.AWS, error)
@@ -160,9 +440,29 @@ func (l Loader) InitConfig(cfg *Config) error {
 		return err
 	}
 
+	if err := l.parseStackPolicies("root", cfg); err != nil {
+		return err
+	}
+
 	cfg.initAwsSettings()
 
-	return l.applyTemplating(cfg)
+	if err := cfg.initWaitOpts("root"); err != nil {
+		return err
+	}
+
+	if err := cfg.validateCreationOpts("root"); err != nil {
+		return err
+	}
+
+	if err := cfg.validateHooks("root"); err != nil {
+		return err
+	}
+
+	if err := l.applyTemplating(cfg); err != nil {
+		return err
+	}
+
+	return inferExportsAndImports(cfg, "root")
 }
 
 func (l Loader) parseBodies(id string, stackCfg *Config) error {
@@ -204,6 +504,55 @@ func (l Loader) parseBodies(id string, stackCfg *Config) error {
 	return nil
 }
 
+func (l Loader) parseStackPolicies(id string, stackCfg *Config) error {
+	for i, nestedStack := range stackCfg.Stacks {
+		nestedStack := nestedStack
+
+		if err := l.parseStackPolicies(i, &nestedStack); err != nil {
+			return err
+		}
+
+		stackCfg.Stacks[i] = nestedStack
+	}
+
+	if stackCfg.StackPolicy == "" {
+		return nil
+	}
+
+	policy, err := l.LoadStackPolicy(stackCfg.StackPolicy)
+	if err != nil {
+		return fmt.Errorf("not possible to parse config for stack %s: %w", id, err)
+	}
+
+	stackCfg.StackPolicy = policy
+
+	return nil
+}
+
+// LoadStackPolicy reads the stack policy given either inline (in json or yaml)
+// or as the path to the file containing it. The policy is returned in json.
+func (l Loader) LoadStackPolicy(policy string) (string, error) {
+	policy = strings.TrimSpace(policy)
+
+	if !strings.ContainsAny(policy, "{\n") {
+		f, err := l.fs.Open(policy)
+		if err != nil {
+			return "", err
+		}
+
+		defer f.Close()
+
+		buf, err := ioutil.ReadAll(f)
+		if err != nil {
+			return "", err
+		}
+
+		policy = string(buf)
+	}
+
+	return awscf.NormalizePolicy(policy)
+}
+
 func (l Loader) decodeConfigs(mainConfig *Config, cfgFiles []string) error {
 	if len(cfgFiles) == 0 {
 		tryCfgFiles := []string{
@@ -249,6 +598,7 @@ func (l Loader) decodeConfigs(mainConfig *Config, cfgFiles []string) error {
 	}
 
 	config := mapstructure.DecoderConfig{
+		DecodeHook:  decodeHook,
 		ErrorUnused: true,
 		Result:      mainConfig,
 	}
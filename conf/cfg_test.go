@@ -214,3 +214,207 @@ func makeTestFile(t *testing.T, ext, content string) (string, func()) {
 func loader() *Loader {
 	return NewLoader(&OsFS{}, &aws.Provider{})
 }
+
+func TestHashIgnoresNestedStacks(t *testing.T) {
+	cfg := Config{Name: "stack", Body: "body", Parameters: map[string]string{"p": "v"}}
+
+	h1, err := cfg.Hash()
+	require.NoError(t, err)
+
+	cfg.Stacks = map[string]Config{"nested": {Name: "nested"}}
+	h2, err := cfg.Hash()
+	require.NoError(t, err)
+	assert.Equal(t, h1, h2)
+
+	cfg.Parameters = map[string]string{"p": "changed"}
+	h3, err := cfg.Hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, h1, h3)
+}
+
+func TestWaitOptsAreInherited(t *testing.T) {
+	cfg := Config{
+		Timeout:      "1h",
+		PollInterval: "10s",
+		Stacks: map[string]Config{
+			"group": {
+				ChangeSetTimeout: "5m",
+				Stacks: map[string]Config{
+					"stack": {Name: "stack", Body: "body", Timeout: "15m"},
+				},
+			},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	opts, err := cfg.Stacks["group"].Stacks["stack"].WaitOpts()
+	require.NoError(t, err)
+	assert.Equal(t, 15*time.Minute, opts.Timeout)
+	assert.Equal(t, 5*time.Minute, opts.ChangeSetTimeout)
+	assert.Equal(t, 10*time.Second, opts.PollInterval)
+}
+
+func TestInvalidTimeout(t *testing.T) {
+	cfg := Config{
+		Stacks: map[string]Config{
+			"stack": {Name: "stack", Body: "body", Timeout: "soon"},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	err := l.InitConfig(&cfg)
+	assert.EqualError(t, err, `not possible to parse config for stack stack: invalid timeout: time: invalid duration "soon"`)
+}
+
+func TestWaitOptsAreOverridden(t *testing.T) {
+	cfg := Config{
+		Timeout: "1h",
+		Stacks: map[string]Config{
+			"group": {
+				PollInterval: "10s",
+				Stacks: map[string]Config{
+					"stack": {Name: "stack", Body: "body", Timeout: "15m", ChangeSetTimeout: "5m"},
+				},
+			},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	require.NoError(t, l.InitConfig(&cfg))
+	require.NoError(t, cfg.OverrideWaitOpts("45m", "", "30s"))
+
+	opts, err := cfg.Stacks["group"].Stacks["stack"].WaitOpts()
+	require.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, opts.Timeout)
+	assert.Equal(t, 5*time.Minute, opts.ChangeSetTimeout)
+	assert.Equal(t, 30*time.Second, opts.PollInterval)
+}
+
+func TestInvalidWaitOptsOverride(t *testing.T) {
+	cfg := Config{Name: "stack", Body: "body"}
+
+	err := cfg.OverrideWaitOpts("", "", "often")
+	assert.EqualError(t, err, `invalid pollInterval: time: invalid duration "often"`)
+}
+
+func TestStackPolicyIsLoadedFromFileAndMergedWithBlockedResources(t *testing.T) {
+	fpath, cleanup := makeTestFile(t, ".yaml", `
+Statement:
+  - Effect: Allow
+    Action: Update:*
+    Principal: "*"
+    Resource: "*"`)
+	defer cleanup()
+
+	cfg := Config{
+		Stacks: map[string]Config{
+			"stack": {Name: "stack", Body: "body", StackPolicy: fpath, Blocked: []string{"Db", "Queue"}},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	policy, err := cfg.Stacks["stack"].Policy()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Statement": [
+		{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"},
+		{"Effect": "Deny", "Action": ["Update:Replace", "Update:Delete"], "Principal": "*",
+			"Resource": ["LogicalResourceId/Db", "LogicalResourceId/Queue"]}
+	]}`, policy)
+}
+
+func TestInlineStackPolicy(t *testing.T) {
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+
+	policy, err := l.LoadStackPolicy(`{"Statement": [{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"}]}`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Statement": [{"Effect": "Allow", "Action": "Update:*", "Principal": "*", "Resource": "*"}]}`, policy)
+
+	_, err = l.LoadStackPolicy("Statement:\n  Effect: Allow")
+	assert.EqualError(t, err, "invalid stack policy: Statement should be a list")
+}
+
+func TestNoPolicyWithoutBlockedResources(t *testing.T) {
+	policy, err := Config{}.Policy()
+	require.NoError(t, err)
+	assert.Empty(t, policy)
+}
+
+func TestCreationOptionsAreInheritedViaDefinitions(t *testing.T) {
+	fpath, cleanup := makeTestFile(t, ".yaml", `
+definitions:
+  protected:
+    enableTerminationProtection: true
+    onFailure: DELETE
+    creationWaitTimeoutInMinutes: 20
+stacks:
+  db:
+    $basedOn: protected
+    name: db
+    includeNestedStacks: true`)
+	defer cleanup()
+
+	cfg := Config{}
+	require.NoError(t, loader().decodeConfigs(&cfg, []string{fpath}))
+
+	db := cfg.Stacks["db"]
+	require.NotNil(t, db.EnableTerminationProtection)
+	assert.True(t, *db.EnableTerminationProtection)
+	assert.Equal(t, "DELETE", db.OnFailure)
+	assert.Equal(t, int64(20), db.CreationWaitTimeoutInMinutes)
+	assert.True(t, db.IncludeNestedStacks)
+}
+
+func TestInvalidCreationOptions(t *testing.T) {
+	cases := []struct {
+		stack Config
+		err   string
+	}{
+		{
+			Config{Name: "stack", Body: "body", OnFailure: "IGNORE"},
+			"not possible to parse config for stack stack: onFailure should be one of ROLLBACK, DELETE or DO_NOTHING",
+		},
+		{
+			Config{Name: "stack", Body: "body", OnFailure: "DELETE", DisableRollback: true},
+			"not possible to parse config for stack stack: onFailure and disableRollback can't be used together",
+		},
+		{
+			Config{Name: "stack", Body: "body", CreationWaitTimeoutInMinutes: -1},
+			"not possible to parse config for stack stack: creationWaitTimeoutInMinutes can't be negative",
+		},
+	}
+
+	for _, c := range cases {
+		cfg := Config{Stacks: map[string]Config{"stack": c.stack}}
+
+		l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+		assert.EqualError(t, l.InitConfig(&cfg), c.err)
+	}
+}
+
+func TestDeleteHooksAreParsed(t *testing.T) {
+	fpath, cleanup := makeTestFile(t, ".yaml", `
+stacks:
+  app:
+    name: app
+    hooks:
+      preDeleteAll:
+        - [echo, group]
+      preDelete:
+        - [aws, s3, rm, s3://bucket, --recursive]
+      postDelete:
+        - [echo, deleted]`)
+	defer cleanup()
+
+	cfg := Config{}
+	require.NoError(t, loader().decodeConfigs(&cfg, []string{fpath}))
+
+	hooks := cfg.Stacks["app"].Hooks
+	assert.Equal(t, HookCmds{{Cmd: []string{"echo", "group"}}}, hooks.PreDeleteAll)
+	assert.Equal(t, HookCmds{{Cmd: []string{"aws", "s3", "rm", "s3://bucket", "--recursive"}}}, hooks.PreDelete)
+	assert.Equal(t, HookCmds{{Cmd: []string{"echo", "deleted"}}}, hooks.PostDelete)
+	assert.Empty(t, hooks.PostDeleteAll)
+}
@@ -0,0 +1,323 @@
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+)
+
+// Dependency is a dependency between two sibling stacks.
+type Dependency struct {
+	// ID of the dependent stack
+	ID string
+	// ID of the stack that ID depends on
+	DependsOn string
+	// Inferred is true if the dependency is not configured explicitly but is
+	// derived from the templates or parameters
+	Inferred bool
+	Reason   string
+}
+
+// Dependencies returns the dependencies between nested stacks. Besides the
+// dependencies configured via dependsOn, the dependencies are inferred from
+// the stack output references in parameters and from the values exported and
+// imported (Fn::ImportValue) by the templates.
+func (cfg Config) Dependencies() []Dependency {
+	exporters := map[string]string{}
+
+	for id, stackCfg := range cfg.Stacks {
+		for _, e := range stackCfg.exports {
+			exporters[e.key()] = id
+		}
+	}
+
+	deps := []Dependency{}
+
+	for id, stackCfg := range cfg.Stacks {
+		for _, depID := range stackCfg.DependsOn {
+			deps = append(deps, Dependency{ID: id, DependsOn: depID, Reason: "dependsOn"})
+		}
+
+		for _, depID := range stackCfg.OutputDependencies() {
+			deps = append(deps, Dependency{ID: id, DependsOn: depID, Inferred: true, Reason: "uses its outputs in parameters"})
+		}
+
+		for _, imp := range stackCfg.imports {
+			if depID, ok := exporters[imp.key()]; ok && depID != id {
+				deps = append(deps, Dependency{ID: id, DependsOn: depID, Inferred: true, Reason: "imports " + imp.name})
+			}
+		}
+	}
+
+	sort.Slice(deps, func(i, j int) bool {
+		if deps[i].ID != deps[j].ID {
+			return deps[i].ID < deps[j].ID
+		}
+
+		if deps[i].DependsOn != deps[j].DependsOn {
+			return deps[i].DependsOn < deps[j].DependsOn
+		}
+
+		return deps[i].Reason < deps[j].Reason
+	})
+
+	return deps
+}
+
+// tplValue is a value exported or imported by a template.
+type tplValue struct {
+	name string
+
+	// scope is the account and the region of the stack. The names of the
+	// exported values are unique only within the account and the region
+	scope string
+
+	// stackID is the ID of the stack whose template exports (or imports) the
+	// value
+	stackID string
+}
+
+func (v tplValue) key() string {
+	return v.scope + " " + v.name
+}
+
+// inferExportsAndImports collects the names of the values exported and
+// imported by the templates of the stack and all its nested stacks. An error
+// is returned if the same value is exported by several stacks.
+func inferExportsAndImports(cfg *Config, id string) error {
+	scope := cfg.tplData.AWS.AccountID + "/" + cfg.tplData.AWS.Region
+	exports, imports := templateExportsAndImports(*cfg)
+
+	cfg.exports = scopedTplValues(exports, scope, id)
+	cfg.imports = scopedTplValues(imports, scope, id)
+
+	exporters := map[string]string{}
+	for _, e := range cfg.exports {
+		exporters[e.key()] = e.stackID
+	}
+
+	nestedIDs := make([]string, 0, len(cfg.Stacks))
+	for nestedID := range cfg.Stacks {
+		nestedIDs = append(nestedIDs, nestedID)
+	}
+
+	sort.Strings(nestedIDs)
+
+	for _, nestedID := range nestedIDs {
+		nestedCfg := cfg.Stacks[nestedID]
+
+		if err := inferExportsAndImports(&nestedCfg, nestedID); err != nil {
+			return err
+		}
+
+		for _, e := range nestedCfg.exports {
+			if exporter, ok := exporters[e.key()]; ok {
+				return fmt.Errorf("value %s is exported by both stack %s and stack %s", e.name, exporter, e.stackID)
+			}
+
+			exporters[e.key()] = e.stackID
+		}
+
+		cfg.exports = append(cfg.exports, nestedCfg.exports...)
+		cfg.imports = append(cfg.imports, nestedCfg.imports...)
+
+		cfg.Stacks[nestedID] = nestedCfg
+	}
+
+	return nil
+}
+
+func scopedTplValues(names []string, scope, stackID string) []tplValue {
+	values := make([]tplValue, len(names))
+
+	for i, name := range names {
+		values[i] = tplValue{name: name, scope: scope, stackID: stackID}
+	}
+
+	return values
+}
+
+func templateExportsAndImports(cfg Config) (exports, imports []string) {
+	if cfg.Body == "" {
+		return nil, nil
+	}
+
+	// the inference is best effort. If the template can't be parsed then
+	// cloudformation will complain about it anyway
+	tpl, err := awscf.ParseTemplate(cfg.Body)
+	if err != nil {
+		return nil, nil
+	}
+
+	root, ok := tpl.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	ev := newTplEvaluator(cfg, root)
+
+	outputs, _ := root["Outputs"].(map[string]interface{})
+	for _, o := range outputs {
+		output, _ := o.(map[string]interface{})
+		export, _ := output["Export"].(map[string]interface{})
+
+		if name, ok := ev.eval(export["Name"]); ok {
+			exports = append(exports, name)
+		}
+	}
+
+	walkTpl(root, func(m map[string]interface{}) {
+		if v, ok := m["Fn::ImportValue"]; ok && len(m) == 1 {
+			if name, ok := ev.eval(v); ok {
+				imports = append(imports, name)
+			}
+		}
+	})
+
+	sort.Strings(exports)
+	sort.Strings(imports)
+
+	return exports, imports
+}
+
+func walkTpl(node interface{}, fn func(map[string]interface{})) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		fn(n)
+
+		for _, v := range n {
+			walkTpl(v, fn)
+		}
+	case []interface{}:
+		for _, v := range n {
+			walkTpl(v, fn)
+		}
+	}
+}
+
+var subVarRe = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// tplEvaluator evaluates the template values that can be evaluated before the
+// stack is deployed, i.e. literals, references to parameters and pseudo
+// parameters as well as Fn::Sub and Fn::Join of those.
+type tplEvaluator struct {
+	refs map[string]string
+}
+
+func newTplEvaluator(cfg Config, tpl map[string]interface{}) tplEvaluator {
+	refs := map[string]string{
+		"AWS::StackName": cfg.Name,
+		"AWS::Region":    cfg.tplData.AWS.Region,
+		"AWS::AccountId": cfg.tplData.AWS.AccountID,
+	}
+
+	params, _ := tpl["Parameters"].(map[string]interface{})
+	for k, p := range params {
+		param, _ := p.(map[string]interface{})
+		if def, ok := param["Default"]; ok {
+			refs[k] = fmt.Sprintf("%v", def)
+		}
+	}
+
+	for k, v := range cfg.Parameters {
+		if !isOutputRef(v) {
+			refs[k] = v
+		}
+	}
+
+	return tplEvaluator{refs: refs}
+}
+
+func (ev tplEvaluator) eval(node interface{}) (string, bool) {
+	switch n := node.(type) {
+	case string:
+		return n, true
+	case map[string]interface{}:
+		if len(n) != 1 {
+			return "", false
+		}
+
+		if ref, ok := n["Ref"].(string); ok {
+			return ev.ref(ref)
+		}
+
+		if sub, ok := n["Fn::Sub"]; ok {
+			return ev.sub(sub)
+		}
+
+		if join, ok := n["Fn::Join"].([]interface{}); ok {
+			return ev.join(join)
+		}
+	}
+
+	return "", false
+}
+
+func (ev tplEvaluator) ref(name string) (string, bool) {
+	v, ok := ev.refs[name]
+	return v, ok && v != ""
+}
+
+func (ev tplEvaluator) sub(arg interface{}) (string, bool) {
+	tpl, ok := arg.(string)
+	vars := map[string]interface{}{}
+
+	if args, isList := arg.([]interface{}); isList && len(args) == 2 {
+		tpl, ok = args[0].(string)
+		vars, _ = args[1].(map[string]interface{})
+	}
+
+	if !ok {
+		return "", false
+	}
+
+	resolved := true
+
+	result := subVarRe.ReplaceAllStringFunc(tpl, func(match string) string {
+		name := match[2 : len(match)-1]
+
+		if strings.HasPrefix(name, "!") {
+			return "${" + name[1:] + "}"
+		}
+
+		if v, ok := vars[name]; ok {
+			s, ok := ev.eval(v)
+			resolved = resolved && ok
+
+			return s
+		}
+
+		s, ok := ev.ref(name)
+		resolved = resolved && ok
+
+		return s
+	})
+
+	return result, resolved
+}
+
+func (ev tplEvaluator) join(args []interface{}) (string, bool) {
+	if len(args) != 2 {
+		return "", false
+	}
+
+	delim, ok := args[0].(string)
+	items, isList := args[1].([]interface{})
+
+	if !ok || !isList {
+		return "", false
+	}
+
+	parts := make([]string, len(items))
+
+	for i, item := range items {
+		if parts[i], ok = ev.eval(item); !ok {
+			return "", false
+		}
+	}
+
+	return strings.Join(parts, delim), true
+}
@@ -0,0 +1,134 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/molecule-man/stack-assembly/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependenciesAreInferredFromExportsAndImports(t *testing.T) {
+	cfg := Config{
+		Parameters: map[string]string{"Env": "dev"},
+		Stacks: map[string]Config{
+			"vpc": {
+				Name: "vpc-{{ .Params.Env }}",
+				Body: `
+Resources:
+  Vpc:
+    Type: AWS::EC2::VPC
+Outputs:
+  VpcId:
+    Value: !Ref Vpc
+    Export:
+      Name: !Sub "${AWS::StackName}-VpcId"`,
+			},
+			"db": {
+				Name: "db",
+				Body: `{
+  "Parameters": {"Env": {"Type": "String"}},
+  "Resources": {
+    "Db": {
+      "Type": "AWS::RDS::DBInstance",
+      "Properties": {
+        "VPC": {"Fn::ImportValue": {"Fn::Join": ["-", ["vpc", {"Ref": "Env"}, "VpcId"]]}}
+      }
+    }
+  },
+  "Outputs": {
+    "Endpoint": {"Value": "db.local", "Export": {"Name": "db-endpoint"}}
+  }
+}`,
+			},
+			"app": {
+				Name:      "app",
+				DependsOn: []string{"vpc"},
+				Body: `
+Resources:
+  App:
+    Type: AWS::ECS::Service
+    Properties:
+      Endpoint: !ImportValue db-endpoint
+      Unknown: !ImportValue
+        Fn::GetAtt: [Foo, Bar]`,
+			},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	assert.Equal(t, []Dependency{
+		{ID: "app", DependsOn: "db", Inferred: true, Reason: "imports db-endpoint"},
+		{ID: "app", DependsOn: "vpc", Reason: "dependsOn"},
+		{ID: "db", DependsOn: "vpc", Inferred: true, Reason: "imports vpc-dev-VpcId"},
+	}, cfg.Dependencies())
+
+	ids, err := cfg.StackIDsSortedByExecOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vpc", "db", "app"}, ids)
+}
+
+func TestDependenciesAreInferredBetweenStackGroups(t *testing.T) {
+	cfg := Config{
+		Stacks: map[string]Config{
+			"network": {
+				Stacks: map[string]Config{
+					"vpc": {Name: "vpc", Body: "Outputs: {Id: {Value: x, Export: {Name: vpc-id}}}"},
+				},
+			},
+			"apps": {
+				Stacks: map[string]Config{
+					"app": {Name: "app", Body: "Resources: {App: {Properties: {Vpc: !ImportValue vpc-id}}}"},
+				},
+			},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	assert.Equal(t, []Dependency{
+		{ID: "apps", DependsOn: "network", Inferred: true, Reason: "imports vpc-id"},
+	}, cfg.Dependencies())
+}
+
+func TestSameValueExportedBySeveralStacksIsRejected(t *testing.T) {
+	cfg := Config{
+		Stacks: map[string]Config{
+			"network": {
+				Stacks: map[string]Config{
+					"vpc": {Name: "vpc", Body: "Outputs: {Id: {Value: x, Export: {Name: vpc-id}}}"},
+				},
+			},
+			"legacy": {Name: "legacy", Body: "Outputs: {Vpc: {Value: y, Export: {Name: vpc-id}}}"},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{}})
+	err := l.InitConfig(&cfg)
+	assert.EqualError(t, err, "value vpc-id is exported by both stack legacy and stack vpc")
+}
+
+func TestSameValueCanBeExportedInDifferentRegions(t *testing.T) {
+	body := "Outputs: {Id: {Value: x, Export: {Name: vpc-id}}}"
+	cfg := Config{
+		Stacks: map[string]Config{
+			"eu": {Name: "vpc", Body: body, Settings: settingsConfig{Aws: aws.Config{Region: "eu-west-1"}}},
+			"us": {Name: "vpc", Body: body, Settings: settingsConfig{Aws: aws.Config{Region: "us-east-1"}}},
+		},
+	}
+
+	l := NewLoader(&OsFS{}, regionAwsProv{})
+	require.NoError(t, l.InitConfig(&cfg))
+	assert.Empty(t, cfg.Dependencies())
+}
+
+// regionAwsProv provides the aws setup of the configured region
+type regionAwsProv struct{}
+
+func (p regionAwsProv) Must(cfg aws.Config) *aws.AWS { return &aws.AWS{Region: cfg.Region} }
+func (p regionAwsProv) New(cfg aws.Config) (*aws.AWS, error) {
+	return &aws.AWS{Region: cfg.Region}, nil
+}
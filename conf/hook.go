@@ -1,46 +1,391 @@
 package conf
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+)
+
+// Operations passed to the hooks in STAS_OPERATION environment variable.
+const (
+	HookOperationCreate = "create"
+	HookOperationUpdate = "update"
+	HookOperationDelete = "delete"
 )
 
-type hookCmd []string
+// Hook is a command executed before or after a stack operation. In the config
+// the hook is either a list (the command with its arguments), a string (the
+// command line executed by the shell) or an object.
+type Hook struct {
+	// Cmd is the command with its arguments.
+	Cmd []string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// Shell is the command line executed by "sh -c". Either Cmd or Shell
+	// has to be provided.
+	Shell string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
 
-type HookCmds []hookCmd
+	// Dir is the working directory of the command.
+	Dir string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// Env are the environment variables passed to the command in addition
+	// to the environment of stas and the variables describing the stack
+	// operation.
+	Env map[string]string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// Timeout is the duration (e.g. "5m") after which the command is killed
+	// together with the processes it started.
+	Timeout string `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+
+	// ContinueOnError makes the failure of the command be reported without
+	// failing the stack operation.
+	ContinueOnError bool `json:",omitempty" yaml:",omitempty" toml:",omitempty"`
+}
+
+type HookCmds []Hook
 
 type HookError struct {
-	cmd hookCmd
+	cmd []string
 	err error
 	out []byte
 }
 
 func (e *HookError) Error() string {
+	if len(e.out) == 0 {
+		return fmt.Sprintf("hook command %v failed with err: %v", e.cmd, e.err)
+	}
+
 	return fmt.Sprintf("hook command %v failed with err: %v, output:\n%s", e.cmd, e.err, string(e.out))
 }
 
-func (h HookCmds) Exec() error {
+// HookContext describes the stack operation the hooks are executed for.
+type HookContext struct {
+	// Operation is one of HookOperationCreate, HookOperationUpdate or
+	// HookOperationDelete. It's empty for the hooks wrapping the stack
+	// together with its nested stacks.
+	Operation   string
+	ChangeSetID string
+	Outputs     []awscf.StackOutput
+
+	// Err is the error of the failed stack operation. It's set for the
+	// OnFailure and OnRollback hooks only, together with the status of the
+	// stack and the events of the failed operation.
+	Err    error
+	Status string
+	Events awscf.StackEvents
+}
+
+// HookEnv returns the environment variables describing the stack operation to
+// the hooks.
+func (cfg Config) HookEnv(hc HookContext) map[string]string {
+	env := map[string]string{}
+
+	for k, v := range map[string]string{
+		"STAS_STACK_NAME":    cfg.Name,
+		"STAS_REGION":        cfg.tplData.AWS.Region,
+		"STAS_ACCOUNT_ID":    cfg.tplData.AWS.AccountID,
+		"STAS_OPERATION":     hc.Operation,
+		"STAS_CHANGE_SET_ID": hc.ChangeSetID,
+		"STAS_STACK_STATUS":  hc.Status,
+	} {
+		if v != "" {
+			env[k] = v
+		}
+	}
+
+	if len(hc.Outputs) > 0 {
+		outputs := make(map[string]string, len(hc.Outputs))
+
+		for _, o := range hc.Outputs {
+			outputs[o.Key] = o.Value
+			env["STAS_OUTPUT_"+o.Key] = o.Value
+		}
+
+		// json encoding of map of strings doesn't fail
+		buf, _ := json.Marshal(outputs)
+		env["STAS_OUTPUTS"] = string(buf)
+	}
+
+	if hc.Err != nil {
+		f := newFailureReport(cfg, hc)
+
+		env["STAS_ERROR"] = f.Error
+		env["STAS_FAILED_STACK"] = f.FailedStack
+
+		if f.RootCause != nil {
+			env["STAS_ROOT_CAUSE_RESOURCE"] = f.RootCause.LogicalResourceID
+			env["STAS_ROOT_CAUSE_TYPE"] = f.RootCause.ResourceType
+			env["STAS_ROOT_CAUSE_STATUS"] = f.RootCause.Status
+			env["STAS_ROOT_CAUSE_REASON"] = f.RootCause.StatusReason
+		}
+	}
+
+	return env
+}
+
+// HookInput returns the json passed to the stdin of the hooks. It's only
+// provided to the OnFailure and OnRollback hooks and describes the failure.
+func (cfg Config) HookInput(hc HookContext) []byte {
+	if hc.Err == nil {
+		return nil
+	}
+
+	// the report consists of strings only, its encoding doesn't fail
+	buf, _ := json.MarshalIndent(newFailureReport(cfg, hc), "", "  ")
+
+	return buf
+}
+
+type failureReport struct {
+	StackName   string         `json:"stackName"`
+	Region      string         `json:"region,omitempty"`
+	AccountID   string         `json:"accountId,omitempty"`
+	Operation   string         `json:"operation,omitempty"`
+	ChangeSetID string         `json:"changeSetId,omitempty"`
+	Status      string         `json:"status,omitempty"`
+	Error       string         `json:"error"`
+	FailedStack string         `json:"failedStack"`
+	RootCause   *failureEvent  `json:"rootCause,omitempty"`
+	Events      []failureEvent `json:"events"`
+}
+
+type failureEvent struct {
+	Timestamp         string `json:"timestamp"`
+	NestedStack       string `json:"nestedStack,omitempty"`
+	LogicalResourceID string `json:"logicalResourceId"`
+	ResourceType      string `json:"resourceType"`
+	Status            string `json:"status"`
+	StatusReason      string `json:"statusReason,omitempty"`
+}
+
+func newFailureEvent(e awscf.StackEvent) failureEvent {
+	return failureEvent{
+		Timestamp:         e.Timestamp.UTC().Format(time.RFC3339),
+		NestedStack:       e.NestedStack,
+		LogicalResourceID: e.LogicalResourceID,
+		ResourceType:      e.ResourceType,
+		Status:            e.Status,
+		StatusReason:      e.StatusReason,
+	}
+}
+
+func newFailureReport(cfg Config, hc HookContext) failureReport {
+	f := failureReport{
+		StackName:   cfg.Name,
+		Region:      cfg.tplData.AWS.Region,
+		AccountID:   cfg.tplData.AWS.AccountID,
+		Operation:   hc.Operation,
+		ChangeSetID: hc.ChangeSetID,
+		Status:      hc.Status,
+		Error:       hc.Err.Error(),
+		FailedStack: cfg.Name,
+		Events:      make([]failureEvent, 0, len(hc.Events)),
+	}
+
+	var ferr *awscf.FailureError
+	if errors.As(hc.Err, &ferr) {
+		// the root cause is reported separately from the error
+		f.Error = ferr.Err.Error()
+		f.FailedStack = ferr.RootCause.StackName
+
+		rc := newFailureEvent(ferr.RootCause.Event)
+		f.RootCause = &rc
+	}
+
+	// the events are reported from oldest to newest
+	for i := len(hc.Events) - 1; i >= 0; i-- {
+		f.Events = append(f.Events, newFailureEvent(hc.Events[i]))
+	}
+
+	return f
+}
+
+// Exec executes the hooks one by one. The output of the hooks is streamed
+// into out. If out is nil, the output is only reported in case of failure.
+func (h HookCmds) Exec(env map[string]string, out io.Writer) error {
+	return h.ExecWithInput(env, nil, out)
+}
+
+// ExecWithInput executes the hooks like Exec. The input is passed to the stdin
+// of every hook.
+func (h HookCmds) ExecWithInput(env map[string]string, input []byte, out io.Writer) error {
 	for _, hc := range h {
-		err := hc.exec()
-		if err != nil {
+		err := hc.exec(env, input, out)
+		if err == nil {
+			continue
+		}
+
+		if !hc.ContinueOnError {
 			return err
 		}
+
+		if out != nil {
+			fmt.Fprintf(out, "%s. Continuing\n", err.Error())
+		}
 	}
 
 	return nil
 }
 
-func (h hookCmd) exec() error {
-	if len(h) == 0 {
+func (h Hook) command() []string {
+	if h.Shell != "" {
+		return []string{"sh", "-c", h.Shell}
+	}
+
+	return h.Cmd
+}
+
+func (h Hook) exec(env map[string]string, input []byte, out io.Writer) error {
+	command := h.command()
+	if len(command) == 0 {
 		return errors.New("hook command is empty")
 	}
 
-	cmd := h[0]
-	out, err := exec.Command(cmd, h[1:]...).CombinedOutput()
+	ctx := context.Background()
+
+	if h.Timeout != "" {
+		timeout, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout of hook command %v: %w", command, err)
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+
+		defer cancel()
+	}
+
+	// the streamed output isn't repeated in the error
+	captured := &bytes.Buffer{}
+	if out == nil {
+		out = captured
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = h.Dir
+	cmd.Env = append(os.Environ(), envList(env)...)
+	cmd.Env = append(cmd.Env, envList(h.Env)...)
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+
+	err := run(ctx, cmd)
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timeout of %s exceeded", h.Timeout)
+	}
 
 	if err != nil {
-		return &HookError{h, err, out}
+		return &HookError{command, err, captured.Bytes()}
+	}
+
+	return nil
+}
+
+// run runs the command and kills it together with the processes it started
+// once ctx is done. Killing only the command would leave the processes it
+// started in background (e.g. `sh -c "sleep 60 &"`) running, and they would
+// keep the output open, so the command would be waited for until they exit.
+func run(ctx context.Context, cmd *exec.Cmd) error {
+	if ctx.Done() == nil {
+		return cmd.Run()
+	}
+
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd.Process)
+		case <-done:
+		}
+	}()
+
+	return cmd.Wait()
+}
+
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+
+	sort.Strings(list)
+
+	return list
+}
+
+// decodeHook allows the hooks to be written in the config as lists (the
+// command with its arguments) and as strings (the shell command line).
+func decodeHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(Hook{}) {
+		return data, nil
+	}
+
+	switch from.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"shell": data}, nil
+	case reflect.Slice:
+		return map[string]interface{}{"cmd": data}, nil
+	}
+
+	return data, nil
+}
+
+func (h Hook) validate() error {
+	if len(h.Cmd) > 0 && h.Shell != "" {
+		return fmt.Errorf("hook %v: either cmd or shell should be provided", h.Cmd)
+	}
+
+	if h.Timeout != "" {
+		if _, err := time.ParseDuration(h.Timeout); err != nil {
+			return fmt.Errorf("invalid timeout of hook %v: %w", h.command(), err)
+		}
+	}
+
+	return nil
+}
+
+func (cfg Config) validateHooks(id string) error {
+	all := []HookCmds{
+		cfg.Hooks.Pre, cfg.Hooks.Post,
+		cfg.Hooks.PreCreate, cfg.Hooks.PostCreate,
+		cfg.Hooks.PreUpdate, cfg.Hooks.PostUpdate,
+		cfg.Hooks.PreDelete, cfg.Hooks.PostDelete,
+		cfg.Hooks.PreDeleteAll, cfg.Hooks.PostDeleteAll,
+		cfg.Hooks.OnFailure, cfg.Hooks.OnRollback,
+	}
+
+	for _, hooks := range all {
+		for _, h := range hooks {
+			if err := h.validate(); err != nil {
+				return fmt.Errorf("not possible to parse config for stack %s: %w", id, err)
+			}
+		}
+	}
+
+	for i, s := range cfg.Stacks {
+		if err := s.validateHooks(i); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -0,0 +1,228 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHookFormsAreParsed(t *testing.T) {
+	fpath, cleanup := makeTestFile(t, ".yaml", `
+hooks:
+  pre:
+    - [echo, list]
+    - echo "$STAS_STACK_NAME"
+    - cmd: [make, deploy]
+      dir: infra
+      env:
+        STAGE: dev
+      timeout: 5m
+      continueOnError: true`)
+	defer cleanup()
+
+	cfg := Config{}
+	require.NoError(t, loader().decodeConfigs(&cfg, []string{fpath}))
+
+	assert.Equal(t, HookCmds{
+		{Cmd: []string{"echo", "list"}},
+		{Shell: `echo "$STAS_STACK_NAME"`},
+		{
+			Cmd:             []string{"make", "deploy"},
+			Dir:             "infra",
+			Env:             map[string]string{"STAGE": "dev"},
+			Timeout:         "5m",
+			ContinueOnError: true,
+		},
+	}, cfg.Hooks.Pre)
+}
+
+func TestInvalidHooksAreRejected(t *testing.T) {
+	app := Config{}
+	app.Hooks.PostUpdate = HookCmds{{Shell: "true", Timeout: "soon"}}
+
+	cfg := Config{Stacks: map[string]Config{"app": app}}
+
+	err := cfg.validateHooks("root")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stack app")
+
+	app.Hooks.PostUpdate = HookCmds{{Cmd: []string{"true"}, Shell: "true"}}
+	cfg.Stacks["app"] = app
+
+	assert.Error(t, cfg.validateHooks("root"))
+}
+
+func TestHookEnv(t *testing.T) {
+	cfg := Config{Name: "app"}
+	cfg.tplData.AWS.Region = "eu-west-1"
+	cfg.tplData.AWS.AccountID = "123456789012"
+
+	env := cfg.HookEnv(HookContext{
+		Operation:   HookOperationUpdate,
+		ChangeSetID: "arn:changeset",
+		Outputs:     []awscf.StackOutput{{Key: "Url", Value: "http://example.com"}},
+	})
+
+	assert.Equal(t, map[string]string{
+		"STAS_STACK_NAME":    "app",
+		"STAS_REGION":        "eu-west-1",
+		"STAS_ACCOUNT_ID":    "123456789012",
+		"STAS_OPERATION":     "update",
+		"STAS_CHANGE_SET_ID": "arn:changeset",
+		"STAS_OUTPUT_Url":    "http://example.com",
+		"STAS_OUTPUTS":       `{"Url":"http://example.com"}`,
+	}, env)
+}
+
+func TestHookExecPassesEnvAndStreamsOutput(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	hooks := HookCmds{
+		{Shell: `echo "$STAS_STACK_NAME $STAGE"`, Env: map[string]string{"STAGE": "dev"}},
+		{Cmd: []string{"pwd"}, Dir: "/"},
+	}
+
+	require.NoError(t, hooks.Exec(map[string]string{"STAS_STACK_NAME": "app"}, out))
+	assert.Equal(t, "app dev\n/\n", out.String())
+}
+
+func TestHookExecFails(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	hooks := HookCmds{
+		{Shell: "echo first; exit 1"},
+		{Shell: "echo second"},
+	}
+
+	err := hooks.Exec(nil, out)
+	require.Error(t, err)
+	assert.Equal(t, "first\n", out.String())
+
+	// the output is part of the error only if it isn't streamed
+	err = hooks.Exec(nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "first")
+}
+
+func TestHookExecContinuesOnError(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	hooks := HookCmds{
+		{Shell: "exit 1", ContinueOnError: true},
+		{Shell: "echo second"},
+	}
+
+	require.NoError(t, hooks.Exec(nil, out))
+	assert.Contains(t, out.String(), "Continuing\nsecond\n")
+}
+
+func TestHookExecTimesOut(t *testing.T) {
+	hooks := HookCmds{{Cmd: []string{"sleep", "5"}, Timeout: "50ms"}}
+
+	err := hooks.Exec(nil, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout of 50ms exceeded")
+}
+
+func TestHookExecTimesOutWhileBackgroundProcessKeepsOutputOpen(t *testing.T) {
+	hooks := HookCmds{{Shell: "sleep 5 &", Timeout: "50ms"}}
+
+	started := time.Now()
+	err := hooks.Exec(nil, nil)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "timeout of 50ms exceeded")
+	assert.Less(t, int64(time.Since(started)), int64(time.Second))
+}
+
+func failedUpdate() (Config, HookContext) {
+	cfg := Config{Name: "app"}
+
+	failed := awscf.StackEvent{
+		LogicalResourceID: "Db",
+		ResourceType:      "AWS::RDS::DBInstance",
+		Status:            "UPDATE_FAILED",
+		StatusReason:      "Invalid storage size",
+		Timestamp:         time.Date(2020, 6, 25, 8, 23, 21, 0, time.UTC),
+	}
+	rolledBack := awscf.StackEvent{
+		LogicalResourceID: "app",
+		ResourceType:      "AWS::CloudFormation::Stack",
+		Status:            "UPDATE_ROLLBACK_COMPLETE",
+		Timestamp:         time.Date(2020, 6, 25, 8, 23, 40, 0, time.UTC),
+	}
+
+	return cfg, HookContext{
+		Operation: HookOperationUpdate,
+		Status:    "UPDATE_ROLLBACK_COMPLETE",
+		Err: &awscf.FailureError{
+			Err:       errors.New("waiter failed"),
+			RootCause: awscf.RootCause{StackName: "app-db", Event: failed},
+		},
+		// newer events appear first
+		Events: awscf.StackEvents{rolledBack, failed},
+	}
+}
+
+func TestHookEnvDescribesFailure(t *testing.T) {
+	cfg, hc := failedUpdate()
+
+	env := cfg.HookEnv(hc)
+
+	assert.Equal(t, "waiter failed", env["STAS_ERROR"])
+	assert.Equal(t, "app-db", env["STAS_FAILED_STACK"])
+	assert.Equal(t, "UPDATE_ROLLBACK_COMPLETE", env["STAS_STACK_STATUS"])
+	assert.Equal(t, "Db", env["STAS_ROOT_CAUSE_RESOURCE"])
+	assert.Equal(t, "AWS::RDS::DBInstance", env["STAS_ROOT_CAUSE_TYPE"])
+	assert.Equal(t, "UPDATE_FAILED", env["STAS_ROOT_CAUSE_STATUS"])
+	assert.Equal(t, "Invalid storage size", env["STAS_ROOT_CAUSE_REASON"])
+}
+
+func TestHookInputDescribesFailure(t *testing.T) {
+	cfg, hc := failedUpdate()
+
+	report := struct {
+		StackName   string
+		Operation   string
+		Status      string
+		Error       string
+		FailedStack string
+		RootCause   struct{ LogicalResourceID string }
+		Events      []struct {
+			Timestamp string
+			Status    string
+		}
+	}{}
+
+	require.NoError(t, json.Unmarshal(cfg.HookInput(hc), &report))
+
+	assert.Equal(t, "app", report.StackName)
+	assert.Equal(t, "update", report.Operation)
+	assert.Equal(t, "UPDATE_ROLLBACK_COMPLETE", report.Status)
+	assert.Equal(t, "waiter failed", report.Error)
+	assert.Equal(t, "app-db", report.FailedStack)
+	assert.Equal(t, "Db", report.RootCause.LogicalResourceID)
+	require.Len(t, report.Events, 2)
+	assert.Equal(t, "UPDATE_FAILED", report.Events[0].Status)
+	assert.Equal(t, "2020-06-25T08:23:21Z", report.Events[0].Timestamp)
+	assert.Equal(t, "UPDATE_ROLLBACK_COMPLETE", report.Events[1].Status)
+}
+
+func TestHookInputIsOnlyProvidedOnFailure(t *testing.T) {
+	assert.Nil(t, Config{Name: "app"}.HookInput(HookContext{Operation: HookOperationUpdate}))
+}
+
+func TestHookExecPassesInput(t *testing.T) {
+	out := &bytes.Buffer{}
+
+	hooks := HookCmds{{Cmd: []string{"cat"}}, {Cmd: []string{"cat"}}}
+
+	require.NoError(t, hooks.ExecWithInput(nil, []byte("report\n"), out))
+	assert.Equal(t, "report\nreport\n", out.String())
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package conf
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes the command the leader of the new process group, so
+// that the processes started by the command can be killed together with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+func killProcessGroup(p *os.Process) {
+	// the group is gone if all its processes have exited already
+	_ = syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
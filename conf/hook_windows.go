@@ -0,0 +1,15 @@
+package conf
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup does nothing as there are no process groups on windows.
+// Only the command itself is killed.
+func setProcessGroup(*exec.Cmd) {}
+
+func killProcessGroup(p *os.Process) {
+	// the process is gone if it has exited already
+	_ = p.Kill()
+}
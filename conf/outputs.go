@@ -0,0 +1,237 @@
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// stackOutputRefRe matches references to outputs of sibling stacks, e.g.
+// {{ .Stacks.vpc.Outputs.SubnetId }}
+var stackOutputRefRe = regexp.MustCompile(`\.Stacks\.(\w+)`)
+
+// stackOutputKeyRe matches the references naming the output key explicitly.
+var stackOutputKeyRe = regexp.MustCompile(`\.Stacks\.(\w+)\.Outputs\.(\w+)`)
+
+type stackTplData struct {
+	Outputs map[string]string
+}
+
+type outputsTplData struct {
+	tplData
+	Stacks map[string]stackTplData
+}
+
+// outputRef is the parameter referring to outputs of the stacks, inherited
+// from the parent config. The outputs are looked up among the siblings of the
+// config declaring the parameter.
+type outputRef struct {
+	tpl      string
+	siblings map[string]Config
+}
+
+// OutputDependencies returns IDs of sibling stacks which outputs are referred
+// to in the parameters of the stack. The references inherited from the parent
+// config are not included, they are dependencies of the parent.
+func (cfg Config) OutputDependencies() []string {
+	seen := map[string]bool{}
+	deps := []string{}
+
+	for _, k := range cfg.deferredParams {
+		if _, ok := cfg.inheritedRefs[k]; ok {
+			continue
+		}
+
+		for _, m := range stackOutputRefRe.FindAllStringSubmatch(cfg.Parameters[k], -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				deps = append(deps, m[1])
+			}
+		}
+	}
+
+	sort.Strings(deps)
+
+	return deps
+}
+
+// ReferredStackNames returns the names of the stacks which outputs are referred
+// to in the parameters of the stack, including the inherited references.
+func (cfg Config) ReferredStackNames() []string {
+	seen := map[string]bool{}
+	names := []string{}
+
+	for _, k := range cfg.deferredParams {
+		siblings := cfg.refSiblings(k)
+
+		for _, m := range stackOutputRefRe.FindAllStringSubmatch(cfg.Parameters[k], -1) {
+			sibling, ok := siblings[m[1]]
+			if ok && !seen[sibling.Name] {
+				seen[sibling.Name] = true
+				names = append(names, sibling.Name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ResolveStackOutputs renders the parameters that refer to outputs of sibling
+// stacks. The referred stacks have to be synchronized before this method is
+// called.
+func (cfg Config) ResolveStackOutputs() (Config, error) {
+	if len(cfg.deferredParams) == 0 {
+		return cfg, nil
+	}
+
+	// the outputs of the stack referred to by multiple parameters are
+	// requested once
+	outputs := map[string]map[string]string{}
+
+	params := make(map[string]string, len(cfg.Parameters))
+	for k, v := range cfg.Parameters {
+		params[k] = v
+	}
+
+	for _, k := range cfg.deferredParams {
+		data, err := cfg.outputsTplData(k, outputs)
+		if err != nil {
+			return cfg, err
+		}
+
+		if err := checkOutputKeys(cfg.Name, k, params[k], data); err != nil {
+			return cfg, err
+		}
+
+		var parsed string
+		if err := parseTplWithOptions(&parsed, params[k], data, "missingkey=error"); err != nil {
+			return cfg, fmt.Errorf("not able to render parameter %s of stack %s: %w", k, cfg.Name, err)
+		}
+
+		params[k] = parsed
+	}
+
+	cfg.Parameters = params
+	cfg.deferredParams = nil
+	cfg.inheritedRefs = nil
+
+	return cfg, nil
+}
+
+func (cfg Config) outputsTplData(param string, outputs map[string]map[string]string) (outputsTplData, error) {
+	data := outputsTplData{
+		tplData: cfg.tplData,
+		Stacks:  map[string]stackTplData{},
+	}
+
+	siblings := cfg.refSiblings(param)
+
+	for _, m := range stackOutputRefRe.FindAllStringSubmatch(cfg.Parameters[param], -1) {
+		id := m[1]
+
+		sibling, ok := siblings[id]
+		if !ok {
+			return data, fmt.Errorf("stack %s refers to outputs of stack %s which is not found among its sibling stacks", cfg.Name, id)
+		}
+
+		if _, ok := outputs[sibling.Name]; !ok {
+			info, err := sibling.Stack().Info()
+			if err != nil {
+				return data, fmt.Errorf("not able to get outputs of stack %s: %w", sibling.Name, err)
+			}
+
+			outputs[sibling.Name] = make(map[string]string, len(info.Outputs()))
+			for _, o := range info.Outputs() {
+				outputs[sibling.Name][o.Key] = o.Value
+			}
+		}
+
+		data.Stacks[id] = stackTplData{Outputs: outputs[sibling.Name]}
+	}
+
+	return data, nil
+}
+
+// checkOutputKeys fails if the parameter refers to the output that the stack
+// doesn't have. Otherwise the missing output would be rendered as an empty
+// value.
+func checkOutputKeys(stackName, param, tpl string, data outputsTplData) error {
+	for _, m := range stackOutputKeyRe.FindAllStringSubmatch(tpl, -1) {
+		if _, ok := data.Stacks[m[1]].Outputs[m[2]]; !ok {
+			return fmt.Errorf("parameter %s of stack %s refers to output %s of stack %s which doesn't exist",
+				param, stackName, m[2], m[1])
+		}
+	}
+
+	return nil
+}
+
+func (cfg Config) refSiblings(param string) map[string]Config {
+	if ref, ok := cfg.inheritedRefs[param]; ok {
+		return ref.siblings
+	}
+
+	return cfg.siblings
+}
+
+// deferOutputRefs records the parameters which refer to outputs of other
+// stacks. These parameters are not rendered while the config is loaded, they
+// are rendered by ResolveStackOutputs right before the stack is synchronized.
+// The references inherited from the parent config are added to the parameters
+// unless the config overrides them. The returned data doesn't include the
+// deferred parameters, they are returned as the references inherited by the
+// nested stacks.
+func (cfg *Config) deferOutputRefs(
+	data tplData,
+	siblings map[string]Config,
+	inherited map[string]outputRef,
+) (tplData, map[string]outputRef) {
+	cfg.siblings = siblings
+	cfg.deferredParams = nil
+	cfg.inheritedRefs = nil
+
+	refs := map[string]outputRef{}
+
+	for k, ref := range inherited {
+		if _, ok := cfg.Parameters[k]; ok {
+			continue
+		}
+
+		if cfg.inheritedRefs == nil {
+			cfg.inheritedRefs = map[string]outputRef{}
+		}
+
+		cfg.Parameters[k] = ref.tpl
+		cfg.inheritedRefs[k] = ref
+	}
+
+	params := make(map[string]string, len(cfg.Parameters))
+
+	for k, v := range cfg.Parameters {
+		if !isOutputRef(v) {
+			params[k] = v
+			continue
+		}
+
+		cfg.deferredParams = append(cfg.deferredParams, k)
+
+		if ref, ok := cfg.inheritedRefs[k]; ok {
+			refs[k] = ref
+		} else {
+			refs[k] = outputRef{tpl: v, siblings: siblings}
+		}
+	}
+
+	sort.Strings(cfg.deferredParams)
+
+	data.Params = params
+	cfg.tplData = data
+
+	return data, refs
+}
+
+func isOutputRef(tpl string) bool {
+	return stackOutputRefRe.MatchString(tpl)
+}
@@ -0,0 +1,160 @@
+package conf
+
+import (
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/molecule-man/stack-assembly/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStackOutputsAreResolvedLazily(t *testing.T) {
+	cfg := Config{
+		Parameters: map[string]string{"Env": "dev"},
+		Stacks: map[string]Config{
+			"vpc": {Name: "vpc-stack", Body: "vpc body"},
+			"app": {
+				Name: "app-stack",
+				Body: "app body",
+				Parameters: map[string]string{
+					"Subnet": "{{ .Stacks.vpc.Outputs.SubnetId }}-{{ .Params.Env }}",
+				},
+			},
+		},
+	}
+
+	cf := &outputsCfMock{outputs: map[string][]*cloudformation.Output{}}
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{CF: cf}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	ordered, err := cfg.StackConfigsSortedByExecOrder()
+	require.NoError(t, err)
+	assert.Equal(t, "vpc-stack", ordered[0].Name)
+	assert.Equal(t, "app-stack", ordered[1].Name)
+
+	app := cfg.Stacks["app"]
+	assert.Equal(t, []string{"vpc"}, app.OutputDependencies())
+
+	// output is only available after vpc stack is synchronized
+	cf.outputs["vpc-stack"] = []*cloudformation.Output{
+		{OutputKey: awssdk.String("SubnetId"), OutputValue: awssdk.String("subnet-123")},
+	}
+
+	resolved, err := app.ResolveStackOutputs()
+	require.NoError(t, err)
+	assert.Equal(t, "subnet-123-dev", resolved.Parameters["Subnet"])
+	assert.Empty(t, resolved.OutputDependencies())
+
+	assert.Equal(t, "{{ .Stacks.vpc.Outputs.SubnetId }}-{{ .Params.Env }}", app.Parameters["Subnet"],
+		"original config should stay intact")
+}
+
+func TestUnknownStackOutputReference(t *testing.T) {
+	cfg := Config{
+		Stacks: map[string]Config{
+			"app": {
+				Name:       "app-stack",
+				Body:       "app body",
+				Parameters: map[string]string{"Subnet": "{{ .Stacks.vpc.Outputs.SubnetId }}"},
+			},
+		},
+	}
+
+	cf := &outputsCfMock{outputs: map[string][]*cloudformation.Output{}}
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{CF: cf}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	_, err := cfg.Stacks["app"].ResolveStackOutputs()
+	assert.EqualError(t, err, "stack app-stack refers to outputs of stack vpc which is not found among its sibling stacks")
+}
+
+func TestMissingStackOutputIsAnError(t *testing.T) {
+	cfg := Config{
+		Stacks: map[string]Config{
+			"vpc": {Name: "vpc-stack", Body: "vpc body"},
+			"app": {
+				Name:       "app-stack",
+				Body:       "app body",
+				Parameters: map[string]string{"Subnet": "{{ .Stacks.vpc.Outputs.SubnetID }}"},
+			},
+		},
+	}
+
+	cf := &outputsCfMock{outputs: map[string][]*cloudformation.Output{
+		"vpc-stack": {{OutputKey: awssdk.String("SubnetId"), OutputValue: awssdk.String("subnet-123")}},
+	}}
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{CF: cf}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	_, err := cfg.Stacks["app"].ResolveStackOutputs()
+	assert.EqualError(t, err, "parameter Subnet of stack app-stack refers to output SubnetID of stack vpc which doesn't exist")
+
+	app := cfg.Stacks["app"]
+	app.Parameters["Subnet"] = "{{ with .Stacks.vpc }}{{ .Outputs.SubnetID }}{{ end }}"
+
+	_, err = app.ResolveStackOutputs()
+	assert.Error(t, err)
+}
+
+func TestStackOutputRefsAreInheritedByNestedStacks(t *testing.T) {
+	cfg := Config{
+		Stacks: map[string]Config{
+			"vpc": {Name: "vpc-stack", Body: "vpc body"},
+			"apps": {
+				Parameters: map[string]string{"Subnet": "{{ .Stacks.vpc.Outputs.SubnetId }}"},
+				Stacks: map[string]Config{
+					"api": {Name: "api-stack", Body: "api body"},
+					"web": {
+						Name:       "web-stack",
+						Body:       "web body",
+						Parameters: map[string]string{"Subnet": "subnet-override"},
+					},
+				},
+			},
+		},
+	}
+
+	cf := &outputsCfMock{outputs: map[string][]*cloudformation.Output{
+		"vpc-stack": {{OutputKey: awssdk.String("SubnetId"), OutputValue: awssdk.String("subnet-123")}},
+	}}
+	l := NewLoader(&OsFS{}, fakeAwsProv{&aws.AWS{CF: cf}})
+	require.NoError(t, l.InitConfig(&cfg))
+
+	assert.Equal(t, []string{"vpc"}, cfg.Stacks["apps"].OutputDependencies())
+
+	api := cfg.Stacks["apps"].Stacks["api"]
+	assert.Empty(t, api.OutputDependencies(), "inherited references are dependencies of the parent")
+	assert.Equal(t, []string{"vpc-stack"}, api.ReferredStackNames())
+
+	resolved, err := api.ResolveStackOutputs()
+	require.NoError(t, err)
+	assert.Equal(t, "subnet-123", resolved.Parameters["Subnet"])
+
+	web := cfg.Stacks["apps"].Stacks["web"]
+	assert.Empty(t, web.ReferredStackNames())
+	assert.Equal(t, "subnet-override", web.Parameters["Subnet"])
+}
+
+type fakeAwsProv struct {
+	a *aws.AWS
+}
+
+func (p fakeAwsProv) Must(cfg aws.Config) *aws.AWS         { return p.a }
+func (p fakeAwsProv) New(cfg aws.Config) (*aws.AWS, error) { return p.a, nil }
+
+type outputsCfMock struct {
+	cloudformationiface.CloudFormationAPI
+	outputs map[string][]*cloudformation.Output
+}
+
+func (cf *outputsCfMock) DescribeStacks(inp *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{{
+			StackName: inp.StackName,
+			Outputs:   cf.outputs[awssdk.StringValue(inp.StackName)],
+		}},
+	}, nil
+}
@@ -20,12 +20,17 @@ type tplData struct {
 
 func (l Loader) applyTemplating(cfg *Config) error {
 	var err error
-	*cfg, err = l.templatizeStackConfig(*cfg, tplData{Params: map[string]string{}})
+	*cfg, err = l.templatizeStackConfig(*cfg, tplData{Params: map[string]string{}}, nil, nil)
 
 	return err
 }
 
-func (l Loader) templatizeStackConfig(cfg Config, data tplData) (Config, error) {
+func (l Loader) templatizeStackConfig(
+	cfg Config,
+	data tplData,
+	siblings map[string]Config,
+	inherited map[string]outputRef,
+) (Config, error) {
 	if err := l.updateAwsSettings(&data, cfg); err != nil {
 		return cfg, err
 	}
@@ -34,7 +39,7 @@ func (l Loader) templatizeStackConfig(cfg Config, data tplData) (Config, error)
 		return cfg, err
 	}
 
-	data.Params = cfg.Parameters
+	data, refs := cfg.deferOutputRefs(data, siblings, inherited)
 
 	if err := templatizeMap(&cfg.Tags, data); err != nil {
 		return cfg, err
@@ -52,8 +57,17 @@ func (l Loader) templatizeStackConfig(cfg Config, data tplData) (Config, error)
 		return cfg, err
 	}
 
+	for id := range cfg.Import {
+		identifier := cfg.Import[id]
+		if err := templatizeMap(&identifier, data); err != nil {
+			return cfg, err
+		}
+
+		cfg.Import[id] = identifier
+	}
+
 	for i, nestedCfg := range cfg.Stacks {
-		templatizedCfg, err := l.templatizeStackConfig(nestedCfg, data)
+		templatizedCfg, err := l.templatizeStackConfig(nestedCfg, data, cfg.Stacks, refs)
 		if err != nil {
 			return cfg, err
 		}
@@ -103,7 +117,20 @@ func templatizeParams(parameters *map[string]string, data tplData) error {
 		}
 	}
 
-	return templatizeMap(parameters, data)
+	for k, v := range *parameters {
+		if isOutputRef(v) {
+			continue
+		}
+
+		var parsed string
+		if err := parseTpl(&parsed, v, data); err != nil {
+			return err
+		}
+
+		(*parameters)[k] = parsed
+	}
+
+	return nil
 }
 
 func templatizeMap(m *map[string]string, data tplData) error {
@@ -124,7 +151,11 @@ func templatizeMap(m *map[string]string, data tplData) error {
 }
 
 func parseTpl(parsed *string, tpl string, data interface{}) error {
-	t, err := template.New(tpl).Funcs(template.FuncMap{
+	return parseTplWithOptions(parsed, tpl, data)
+}
+
+func parseTplWithOptions(parsed *string, tpl string, data interface{}, opts ...string) error {
+	t, err := template.New(tpl).Option(opts...).Funcs(template.FuncMap{
 		"Exec": func(cmd string, args ...string) (string, error) {
 			out, err := exec.Command(cmd, args...).CombinedOutput()
 			if err != nil {
@@ -1,62 +1,295 @@
 package assembly
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/molecule-man/stack-assembly/awscf"
 	"github.com/molecule-man/stack-assembly/cli"
 	"github.com/molecule-man/stack-assembly/conf"
 )
 
-func (sa *SA) Delete(cfg conf.Config, nonInteractive bool) error {
-	action := &deleteAction{sa, sa.cli, nonInteractive}
+// DeleteOpts are the options of the stacks deletion.
+type DeleteOpts struct {
+	NonInteractive bool
+
+	// Concurrency is the maximum number of stacks deleted at the same time.
+	Concurrency int
+
+	// ForceUnprotect makes the termination protection of the stacks be
+	// disabled before the deletion. Otherwise nothing is deleted if any of the
+	// stacks is protected.
+	ForceUnprotect bool
+}
+
+func (sa *SA) Delete(ctx context.Context, cfg conf.Config, opts DeleteOpts) error {
+	s := *sa
+	if opts.Concurrency > 1 {
+		s = s.withLockedOutput()
+	}
+
+	action := &deleteAction{
+		ctx:            ctx,
+		sa:             &s,
+		cli:            s.cli,
+		nonInteractive: opts.NonInteractive,
+		concurrency:    opts.Concurrency,
+		forceUnprotect: opts.ForceUnprotect,
+		sched:          newScheduler(opts.Concurrency),
+		stacks:         map[string]*awscf.Stack{},
+		consumers:      map[string]map[string][]string{},
+	}
+
+	for _, c := range stackConfigsToDelete(cfg) {
+		action.stacks[c.Name] = c.Stack()
+	}
+
+	if err := action.checkExportConsumers(cfg); err != nil {
+		return err
+	}
+
+	if err := action.checkTerminationProtection(cfg); err != nil {
+		return err
+	}
+
 	return action.delete(cfg)
 }
 
 type deleteAction struct {
+	ctx            context.Context
 	sa             *SA
 	cli            *cli.CLI
 	nonInteractive bool
+	concurrency    int
+	forceUnprotect bool
+	sched          *scheduler
+
+	// stacks are the stacks to be deleted by name. They are shared by the
+	// pre-flight check and the deletion so that the stacks are described
+	// only once. The map is built before the deletion starts, so it's only
+	// read by the concurrently deleted stacks
+	stacks map[string]*awscf.Stack
+
+	// consumers map the names of the stacks to be deleted to their exports
+	// and the names of the stacks importing them
+	consumers map[string]map[string][]string
+
+	// promptMu prevents prompts of concurrently deleted stacks from being
+	// mixed up
+	promptMu sync.Mutex
+}
+
+// checkExportConsumers fails if the values exported by the stacks to be
+// deleted are imported by the stacks that are not deleted. The check is done
+// before any stack is deleted.
+func (a *deleteAction) checkExportConsumers(cfg conf.Config) error {
+	cfgs := stackConfigsToDelete(cfg)
+
+	deleted := make(map[string]bool, len(cfgs))
+	for _, c := range cfgs {
+		deleted[c.Name] = true
+	}
+
+	inUse := []string{}
+
+	for _, c := range cfgs {
+		stack := a.stacks[c.Name]
+
+		exists, err := stack.Exists()
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			continue
+		}
+
+		consumers, err := stack.ExportConsumers()
+		if err != nil {
+			return err
+		}
+
+		a.consumers[c.Name] = consumers
+
+		for export, importers := range consumers {
+			for _, importer := range importers {
+				if !deleted[importer] {
+					inUse = append(inUse, fmt.Sprintf("%s imports %s exported by %s", importer, export, c.Name))
+				}
+			}
+		}
+	}
+
+	if len(inUse) > 0 {
+		sort.Strings(inUse)
+
+		return fmt.Errorf("nothing is deleted because the exports of the stacks are used by the stacks "+
+			"that are not being deleted:\n  %s", strings.Join(inUse, "\n  "))
+	}
+
+	return nil
+}
+
+// checkTerminationProtection fails if any of the stacks to be deleted is
+// protected from termination unless the protection is forced to be disabled.
+// The check is done before any stack is deleted.
+func (a *deleteAction) checkTerminationProtection(cfg conf.Config) error {
+	if a.forceUnprotect {
+		return nil
+	}
+
+	protected := []string{}
+
+	for _, c := range stackConfigsToDelete(cfg) {
+		info, err := a.stacks[c.Name].Info()
+		if err == awscf.ErrStackDoesntExist {
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if info.TerminationProtection() {
+			protected = append(protected, c.Name)
+		}
+	}
+
+	if len(protected) > 0 {
+		return fmt.Errorf("nothing is deleted because the stacks are protected from termination. "+
+			"Use --force-unprotect to delete them:\n  %s", strings.Join(protected, "\n  "))
+	}
+
+	return nil
+}
+
+// stackConfigsToDelete returns the config of the stack and of all its nested
+// stacks having a name.
+func stackConfigsToDelete(cfg conf.Config) []conf.Config {
+	cfgs := []conf.Config{}
+
+	if cfg.Name != "" {
+		cfgs = append(cfgs, cfg)
+	}
+
+	ids := make([]string, 0, len(cfg.Stacks))
+	for id := range cfg.Stacks {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		cfgs = append(cfgs, stackConfigsToDelete(cfg.Stacks[id])...)
+	}
+
+	return cfgs
 }
 
 func (a *deleteAction) delete(cfg conf.Config) error {
-	ss, err := cfg.StackConfigsSortedByExecOrder()
-	if err != nil {
+	if err := a.sa.runGroupHooks(cfg, cfg.Hooks.PreDeleteAll); err != nil {
 		return err
 	}
 
-	// reverse order of stack configs
-	for i, j := 0, len(ss)-1; i < j; i, j = i+1, j-1 {
-		ss[i], ss[j] = ss[j], ss[i]
+	err := a.deleteNested(cfg)
+	if err != nil {
+		return err
 	}
 
-	for _, s := range ss {
-		nestedErr := a.delete(s)
-		if nestedErr != nil {
-			return nestedErr
+	if cfg.Name != "" {
+		err = a.sched.run(func() error {
+			return a.deleteStack(cfg)
+		})
+		if err != nil {
+			return err
 		}
 	}
 
-	if cfg.Name == "" {
+	return a.sa.runGroupHooks(cfg, cfg.Hooks.PostDeleteAll)
+}
+
+func (a *deleteAction) deleteNested(cfg conf.Config) error {
+	if a.concurrency <= 1 {
+		ss, err := cfg.StackConfigsSortedByExecOrder()
+		if err != nil {
+			return err
+		}
+
+		// reverse order of stack configs
+		for i, j := 0, len(ss)-1; i < j; i, j = i+1, j-1 {
+			ss[i], ss[j] = ss[j], ss[i]
+		}
+
+		for _, s := range ss {
+			nestedErr := a.delete(s)
+			if nestedErr != nil {
+				return nestedErr
+			}
+		}
+
 		return nil
 	}
 
+	levels, err := cfg.StackConfigsByExecLevel()
+	if err != nil {
+		return err
+	}
+
+	for i := len(levels) - 1; i >= 0; i-- {
+		level := levels[i]
+
+		err := a.sched.each(len(level), func(j int) error {
+			return a.delete(level[j])
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *deleteAction) deleteStack(cfg conf.Config) error {
+	if a.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
 	logger := a.cli.PrefixedLogger(fmt.Sprintf("[%s] ", cfg.Name))
 
-	stack := cfg.Stack()
+	stack := a.stacks[cfg.Name]
 
 	exists, err := stack.Exists()
-	MustSucceed(err)
+	if err != nil {
+		return err
+	}
 
 	if !exists {
 		logger.Info("Stack doesn't exist")
 		return nil
 	}
 
-	logger.Warnf("Stack %s is about to be deleted", cfg.Name)
+	info, err := stack.Info()
+	if err != nil {
+		return err
+	}
+
+	protection := "disabled"
+
+	if info.TerminationProtection() {
+		protection = "enabled, it will be disabled"
+	}
+
+	a.promptMu.Lock()
 
-	err = a.ask(stack)
+	logger.Warnf("Stack %s is about to be deleted (termination protection: %s)", cfg.Name, protection)
+
+	err = a.ask(stack, a.consumers[cfg.Name])
+
+	a.promptMu.Unlock()
 
 	if err == errSkipDelete {
 		return nil
@@ -66,17 +299,38 @@ func (a *deleteAction) delete(cfg conf.Config) error {
 		return err
 	}
 
-	err = stack.Delete()
+	// the outputs of the deleted stack are the ones described before the
+	// deletion
+	hc := conf.HookContext{Operation: conf.HookOperationDelete, Outputs: info.Outputs()}
+
+	if err := runStackHooks(cfg, cfg.Hooks.PreDelete, hc, nil, logger); err != nil {
+		return err
+	}
+
+	if info.TerminationProtection() {
+		logger.Info("Disabling termination protection")
+
+		if err := stack.SetTerminationProtection(false); err != nil {
+			return err
+		}
+	}
+
+	err = stack.Delete(a.ctx)
+	if a.ctx.Err() != nil {
+		logger.Warn("Stopped waiting for the deletion. Cloudformation keeps deleting the stack")
+		return ErrInterrupted
+	}
+
 	if err != nil {
 		return err
 	}
 
 	logger.Print(a.cli.Color.Success("Stack is deleted successfully"))
 
-	return nil
+	return runStackHooks(cfg, cfg.Hooks.PostDelete, hc, nil, logger)
 }
 
-func (a *deleteAction) ask(stack *awscf.Stack) error {
+func (a *deleteAction) ask(stack *awscf.Stack, consumers map[string][]string) error {
 	if a.nonInteractive {
 		return nil
 	}
@@ -102,7 +356,9 @@ func (a *deleteAction) ask(stack *awscf.Stack) error {
 			Description:   "[i]nfo (show stack info)",
 			TriggerInputs: []string{"i", "info"},
 			Action: func() {
-				actionErr = a.sa.Info(stack)
+				if actionErr = a.sa.Info(stack); actionErr == nil {
+					a.showExportConsumers(consumers)
+				}
 			},
 		}, {
 			Description:   "[s]kip",
@@ -130,4 +386,27 @@ func (a *deleteAction) ask(stack *awscf.Stack) error {
 	return nil
 }
 
+func (a *deleteAction) showExportConsumers(consumers map[string][]string) {
+	if len(consumers) == 0 {
+		return
+	}
+
+	exports := make([]string, 0, len(consumers))
+	for e := range consumers {
+		exports = append(exports, e)
+	}
+
+	sort.Strings(exports)
+
+	t := cli.NewTable()
+	t.Header("Export", "Imported by")
+
+	for _, e := range exports {
+		t.Row(e, strings.Join(consumers[e], ", "))
+	}
+
+	a.cli.Print("==== EXPORTS IMPORTED BY OTHER STACKS ====")
+	a.cli.Print(t.Render())
+}
+
 var errSkipDelete = errors.New("deletion skipped")
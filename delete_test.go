@@ -0,0 +1,120 @@
+package assembly
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/conf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNothingIsDeletedIfAnyStackIsProtected(t *testing.T) {
+	cf := &deletedCF{protected: map[string]bool{"stas-db": true}}
+	sa, _ := testSA()
+
+	err := sa.Delete(context.Background(), dependentStacksConfig(t, cf), DeleteOpts{NonInteractive: true, Concurrency: 1})
+
+	assert.EqualError(t, err, "nothing is deleted because the stacks are protected from termination. "+
+		"Use --force-unprotect to delete them:\n  stas-db")
+	assert.Empty(t, cf.deleted)
+}
+
+func TestProtectedStacksAreDeletedIfUnprotectionIsForced(t *testing.T) {
+	cf := &deletedCF{protected: map[string]bool{"stas-db": true}}
+	sa, _ := testSA()
+
+	err := sa.Delete(context.Background(), dependentStacksConfig(t, cf),
+		DeleteOpts{NonInteractive: true, Concurrency: 1, ForceUnprotect: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stas-app", "stas-db"}, cf.deleted)
+	assert.Equal(t, []string{"stas-db"}, cf.unprotected)
+}
+
+func TestStacksAreDeletedConcurrently(t *testing.T) {
+	cf := &deletedCF{protected: map[string]bool{}}
+	sa, _ := testSA()
+
+	cfg := conf.Config{Stacks: map[string]conf.Config{
+		"db":    {Name: "stas-db", Body: "Resources: {}"},
+		"queue": {Name: "stas-queue", Body: "Resources: {}"},
+		"app":   {Name: "stas-app", Body: "Resources: {}", DependsOn: []string{"db", "queue"}},
+	}}
+	require.NoError(t, conf.NewLoader(&conf.OsFS{}, fakeAwsProv{cf}).InitConfig(&cfg))
+
+	err := sa.Delete(context.Background(), cfg, DeleteOpts{NonInteractive: true, Concurrency: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"stas-app", "stas-db", "stas-queue"}, cf.deleted)
+}
+
+func TestDeletionFailsIfStackCantBeDescribed(t *testing.T) {
+	sa, _ := testSA()
+	action := &deleteAction{
+		ctx:    context.Background(),
+		sa:     &sa,
+		cli:    sa.cli,
+		stacks: map[string]*awscf.Stack{"stas-db": awscf.NewStack("stas-db", &unreachableCF{}, nil)},
+	}
+
+	assert.Equal(t, errUnreachable, action.deleteStack(conf.Config{Name: "stas-db"}))
+}
+
+// deletedCF fakes the stacks that exist and are deleted successfully.
+type deletedCF struct {
+	cloudformationiface.CloudFormationAPI
+
+	mu          sync.Mutex
+	protected   map[string]bool
+	deleted     []string
+	unprotected []string
+}
+
+func (cf *deletedCF) DescribeStacks(inp *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	return &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{{
+		StackName:                   inp.StackName,
+		StackStatus:                 awssdk.String(cloudformation.StackStatusCreateComplete),
+		EnableTerminationProtection: awssdk.Bool(cf.protected[awssdk.StringValue(inp.StackName)]),
+	}}}, nil
+}
+
+func (cf *deletedCF) UpdateTerminationProtection(
+	inp *cloudformation.UpdateTerminationProtectionInput,
+) (*cloudformation.UpdateTerminationProtectionOutput, error) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	cf.protected[awssdk.StringValue(inp.StackName)] = awssdk.BoolValue(inp.EnableTerminationProtection)
+	cf.unprotected = append(cf.unprotected, awssdk.StringValue(inp.StackName))
+
+	return &cloudformation.UpdateTerminationProtectionOutput{}, nil
+}
+
+func (cf *deletedCF) DeleteStack(inp *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	cf.deleted = append(cf.deleted, awssdk.StringValue(inp.StackName))
+	sort.Strings(cf.deleted)
+
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func (cf *deletedCF) WaitUntilStackDeleteCompleteWithContext(
+	awssdk.Context,
+	*cloudformation.DescribeStacksInput,
+	...request.WaiterOption,
+) error {
+	return nil
+}
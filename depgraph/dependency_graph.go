@@ -116,3 +116,90 @@ func (dg *DepGraph) visit(n node, resolved *[]string) error {
 
 	return nil
 }
+
+// Levels groups the nodes added via Add method into levels (waves). Nodes of
+// the first level have no dependencies, nodes of every next level depend only
+// on the nodes of the previous levels. Nodes within one level don't depend on
+// each other and therefore can be processed concurrently.
+func (dg *DepGraph) Levels() ([][]string, error) {
+	levels := [][]string{}
+	inDegree := make(map[string]int, len(dg.nodes))
+
+	for id, n := range dg.nodes {
+		if n.id == "" {
+			return levels, fmt.Errorf("bad input to dependency resolver. Node with id '%s' has not been registered", id)
+		}
+
+		if _, ok := inDegree[id]; !ok {
+			inDegree[id] = 0
+		}
+
+		for _, nextID := range n.next {
+			inDegree[nextID]++
+		}
+	}
+
+	level := []string{}
+
+	for id, degree := range inDegree {
+		if degree == 0 {
+			level = append(level, id)
+		}
+	}
+
+	processed := 0
+
+	for len(level) > 0 {
+		sort.Strings(level)
+		levels = append(levels, level)
+		processed += len(level)
+
+		nextLevel := []string{}
+
+		for _, id := range level {
+			for _, nextID := range dg.nodes[id].next {
+				inDegree[nextID]--
+
+				if inDegree[nextID] == 0 {
+					nextLevel = append(nextLevel, nextID)
+				}
+			}
+		}
+
+		level = nextLevel
+	}
+
+	if processed != len(dg.nodes) {
+		return levels, ErrCyclicGraph
+	}
+
+	return levels, nil
+}
+
+// Dependents returns the IDs of all the nodes that depend on the node with the
+// given id either directly or transitively. The IDs are sorted.
+func (dg *DepGraph) Dependents(id string) []string {
+	seen := map[string]bool{}
+	queue := append([]string{}, dg.nodes[id].next...)
+
+	for len(queue) > 0 {
+		nextID := queue[0]
+		queue = queue[1:]
+
+		if seen[nextID] {
+			continue
+		}
+
+		seen[nextID] = true
+		queue = append(queue, dg.nodes[nextID].next...)
+	}
+
+	dependents := make([]string, 0, len(seen))
+	for depID := range seen {
+		dependents = append(dependents, depID)
+	}
+
+	sort.Strings(dependents)
+
+	return dependents
+}
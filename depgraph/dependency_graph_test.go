@@ -144,3 +144,56 @@ func TestDuplications(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []string{"1", "2", "3"}, resolved)
 }
+
+func TestLevels(t *testing.T) {
+	dg := DepGraph{}
+	dg.Add("vpc", []string{})
+	dg.Add("sns", []string{})
+	dg.Add("db", []string{"vpc"})
+	dg.Add("app", []string{"db", "sns"})
+	dg.Add("cdn", []string{"vpc"})
+	dg.Add("dns", []string{"app", "cdn"})
+
+	levels, err := dg.Levels()
+
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"sns", "vpc"},
+		{"cdn", "db"},
+		{"app"},
+		{"dns"},
+	}, levels)
+}
+
+func TestLevelsOfCyclicGraph(t *testing.T) {
+	dg := DepGraph{}
+	dg.Add("1", []string{})
+	dg.Add("2", []string{"1", "3"})
+	dg.Add("3", []string{"2"})
+
+	_, err := dg.Levels()
+	assert.Equal(t, ErrCyclicGraph, err)
+}
+
+func TestLevelsInvalidInput(t *testing.T) {
+	dg := DepGraph{}
+	dg.Add("1", []string{})
+	dg.Add("2", []string{"1", "3"})
+
+	_, err := dg.Levels()
+	assert.Error(t, err)
+}
+
+func TestDependents(t *testing.T) {
+	dg := DepGraph{}
+	dg.Add("vpc", []string{})
+	dg.Add("sns", []string{})
+	dg.Add("db", []string{"vpc"})
+	dg.Add("app", []string{"db", "sns"})
+	dg.Add("cdn", []string{"vpc"})
+	dg.Add("dns", []string{"app", "cdn"})
+
+	assert.Equal(t, []string{"app", "cdn", "db", "dns"}, dg.Dependents("vpc"))
+	assert.Equal(t, []string{"app", "dns"}, dg.Dependents("sns"))
+	assert.Equal(t, []string{}, dg.Dependents("dns"))
+}
@@ -1,36 +1,136 @@
 package assembly
 
 import (
+	"encoding/json"
+
 	"github.com/molecule-man/stack-assembly/awscf"
 	"github.com/molecule-man/stack-assembly/conf"
 )
 
-func (sa SA) Diff(cfg conf.Config) error {
+// Output formats of the diff.
+const (
+	DiffOutputText     = "text"
+	DiffOutputJSON     = "json"
+	DiffOutputMarkdown = "markdown"
+)
+
+// DiffOpts are the options of the diff.
+type DiffOpts struct {
+	// Mode is the mode of the template body diff: either awscf.DiffModeText
+	// (default) or awscf.DiffModeSemantic.
+	Mode string
+
+	// Output is the format of the diff: DiffOutputText (default),
+	// DiffOutputJSON or DiffOutputMarkdown.
+	Output string
+}
+
+// Diff shows the diff of the stacks of the config and tells whether any of
+// the stacks has changes.
+func (sa SA) Diff(cfg conf.Config, opts DiffOpts) (bool, error) {
+	if opts.Output == "" || opts.Output == DiffOutputText {
+		return sa.diffText(cfg, opts)
+	}
+
+	diffs := []awscf.StackDiff{}
+	if err := sa.collectDiffs(cfg, opts, &diffs); err != nil {
+		return false, err
+	}
+
+	hasChanges := false
+
+	for _, d := range diffs {
+		if d.HasChanges() {
+			hasChanges = true
+		}
+	}
+
+	if opts.Output == DiffOutputMarkdown {
+		sa.cli.Print(markdownDiff(diffs))
+		return hasChanges, nil
+	}
+
+	enc := json.NewEncoder(sa.cli.Writer)
+	enc.SetIndent("", "  ")
+
+	return hasChanges, enc.Encode(diffs)
+}
+
+func (sa SA) diffText(cfg conf.Config, opts DiffOpts) (bool, error) {
+	hasChanges := false
+
 	for _, childCfg := range cfg.Stacks {
-		err := sa.Diff(childCfg)
+		changed, err := sa.diffText(childCfg, opts)
 		if err != nil {
-			return err
+			return hasChanges, err
 		}
+
+		hasChanges = hasChanges || changed
 	}
 
 	if cfg.Body == "" {
-		return nil
+		return hasChanges, nil
 	}
 
-	cs := cfg.ChangeSet()
+	var diff string
 
-	defer func() {
-		if closeErr := cs.Close(); closeErr != nil {
-			sa.cli.Warnf("Error while cleaning up: %s", closeErr.Error())
+	err := sa.withDiffChangeSet(cfg, func(cs *awscf.ChangeSet) error {
+		var err error
+		diff, err = awscf.ChSetDiff{Color: sa.cli.Color, Mode: opts.Mode}.Diff(cs)
+
+		return err
+	})
+	if err != nil {
+		return hasChanges, err
+	}
+
+	sa.cli.Print(diff)
+
+	return hasChanges || diff != "", nil
+}
+
+// collectDiffs collects the diffs of the stack and its nested stacks in the
+// order of synchronization.
+func (sa SA) collectDiffs(cfg conf.Config, opts DiffOpts, diffs *[]awscf.StackDiff) error {
+	if cfg.Body != "" {
+		err := sa.withDiffChangeSet(cfg, func(cs *awscf.ChangeSet) error {
+			sd, err := awscf.ChSetDiff{Mode: opts.Mode}.StackDiff(cs)
+			*diffs = append(*diffs, sd)
+
+			return err
+		})
+		if err != nil {
+			return err
 		}
-	}()
+	}
 
-	diff, err := awscf.ChSetDiff{Color: sa.cli.Color}.Diff(cs)
+	nestedIDs, err := cfg.StackIDsSortedByExecOrder()
 	if err != nil {
 		return err
 	}
 
-	sa.cli.Print(diff)
+	for _, id := range nestedIDs {
+		if err := sa.collectDiffs(cfg.Stacks[id], opts, diffs); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+func (sa SA) withDiffChangeSet(cfg conf.Config, fn func(*awscf.ChangeSet) error) error {
+	cfg, err := cfg.ResolveStackOutputs()
+	if err != nil {
+		return err
+	}
+
+	cs := cfg.ChangeSet()
+
+	defer func() {
+		if closeErr := cs.Close(); closeErr != nil {
+			sa.cli.Warnf("Error while cleaning up: %s", closeErr.Error())
+		}
+	}()
+
+	return fn(cs)
+}
@@ -0,0 +1,86 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+)
+
+// markdownDiff renders the diffs as markdown suitable for a pull request
+// comment. Every changed stack gets a collapsible section.
+func markdownDiff(diffs []awscf.StackDiff) string {
+	b := &strings.Builder{}
+
+	for _, d := range diffs {
+		if !d.HasChanges() {
+			fmt.Fprintf(b, "**%s**: no changes\n\n", d.Stack)
+			continue
+		}
+
+		fmt.Fprintf(b, "<details>\n<summary><b>%s</b>: %s</summary>\n\n", d.Stack, markdownSummary(d))
+
+		writeMarkdownKeyChanges(b, "Parameters", "Parameter", d.Parameters)
+		writeMarkdownKeyChanges(b, "Tags", "Tag", d.Tags)
+
+		if len(d.Resources) > 0 {
+			b.WriteString("#### Resources\n\n| Change | Resource ID | Resource Type |\n| --- | --- | --- |\n")
+
+			for _, r := range d.Resources {
+				fmt.Fprintf(b, "| %s | %s | %s |\n", r.Kind, mdCell(r.LogicalResourceID), mdCell(r.ResourceType))
+			}
+
+			b.WriteString("\n")
+		}
+
+		if d.Body != "" {
+			fmt.Fprintf(b, "#### Template\n\n```diff\n%s\n```\n\n", strings.TrimSpace(d.Body))
+		}
+
+		b.WriteString("</details>\n\n")
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func markdownSummary(d awscf.StackDiff) string {
+	parts := []string{}
+
+	for _, p := range []struct {
+		count int
+		name  string
+	}{
+		{len(d.Parameters), "parameter"},
+		{len(d.Tags), "tag"},
+		{len(d.Resources), "resource"},
+	} {
+		if p.count > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s change(s)", p.count, p.name))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "template changes"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func writeMarkdownKeyChanges(b *strings.Builder, title, keyName string, changes []awscf.KeyChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	fmt.Fprintf(b, "#### %s\n\n| Change | %s | Old | New |\n| --- | --- | --- | --- |\n", title, keyName)
+
+	for _, c := range changes {
+		fmt.Fprintf(b, "| %s | %s | %s | %s |\n", c.Kind, mdCell(c.Key), mdCell(c.Old), mdCell(c.New))
+	}
+
+	b.WriteString("\n")
+}
+
+// mdCell escapes the value so that it can be used in a markdown table cell.
+func mdCell(v string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(v)
+}
@@ -0,0 +1,178 @@
+package assembly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// Output formats of the drift detection.
+const (
+	DriftOutputText = "text"
+	DriftOutputJSON = "json"
+)
+
+// DriftOpts are the options of the drift detection.
+type DriftOpts struct {
+	// Output is the format of the result: DriftOutputText (default) or
+	// DriftOutputJSON.
+	Output string
+}
+
+// DetectDrift detects the drift of the stacks of the config and tells whether
+// any of the stacks has drifted. The stacks that don't exist are skipped.
+func (sa SA) DetectDrift(ctx context.Context, cfg conf.Config, opts DriftOpts) (bool, error) {
+	drifts := []awscf.Drift{}
+
+	err := sa.detectDriftRecursively(ctx, cfg, opts, &drifts)
+	if err != nil {
+		return false, err
+	}
+
+	drifted := false
+
+	for _, d := range drifts {
+		if d.Drifted() {
+			drifted = true
+		}
+	}
+
+	if opts.Output == DriftOutputJSON {
+		enc := json.NewEncoder(sa.cli.Writer)
+		enc.SetIndent("", "  ")
+
+		return drifted, enc.Encode(drifts)
+	}
+
+	return drifted, nil
+}
+
+func (sa SA) detectDriftRecursively(ctx context.Context, cfg conf.Config, opts DriftOpts, drifts *[]awscf.Drift) error {
+	if cfg.Body != "" {
+		if ctx.Err() != nil {
+			return ErrInterrupted
+		}
+
+		logger := sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", cfg.Name))
+		if opts.Output == DriftOutputJSON {
+			logger = sa.quietLogger(cfg.Name)
+		}
+
+		drift, found, err := sa.detectStackDrift(ctx, cfg.Stack(), logger)
+		if err != nil {
+			return err
+		}
+
+		if !found {
+			logger.Info("Stack doesn't exist")
+		} else {
+			*drifts = append(*drifts, drift)
+
+			if opts.Output != DriftOutputJSON {
+				sa.showDrift(drift)
+			}
+		}
+	}
+
+	nestedIDs, err := cfg.StackIDsSortedByExecOrder()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range nestedIDs {
+		if err := sa.detectDriftRecursively(ctx, cfg.Stacks[id], opts, drifts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// detectStackDrift detects the drift of the stack. found is false if the
+// stack doesn't exist.
+func (sa SA) detectStackDrift(
+	ctx context.Context,
+	stack *awscf.Stack,
+	logger *cli.Logger,
+) (drift awscf.Drift, found bool, err error) {
+	exists, err := stack.Exists()
+	if err != nil || !exists {
+		return drift, false, err
+	}
+
+	logger.Info("Detecting drift")
+
+	drift, err = stack.DetectDrift(ctx)
+
+	return drift, err == nil, err
+}
+
+// quietLogger is the logger that doesn't pollute the machine readable output.
+func (sa SA) quietLogger(name string) *cli.Logger {
+	quiet := *sa.cli
+	quiet.Writer = sa.cli.Errorer
+
+	return quiet.PrefixedLogger(fmt.Sprintf("[%s] ", name))
+}
+
+func (sa SA) showDrift(drift awscf.Drift) {
+	sa.cli.Printf("[%s] Drift status: %s", drift.StackName, sa.colorizedDriftStatus(drift.Status))
+
+	t := cli.NewTable()
+	t.Header("Resource ID", "Resource Type", "Drift status")
+
+	for _, r := range drift.Resources {
+		t.Row(r.LogicalResourceID, r.ResourceType, sa.colorizedDriftStatus(r.Status))
+	}
+
+	sa.cli.Print(t.Render())
+
+	diffs := cli.NewTable()
+	diffs.Header("Resource ID", "Property", "Difference", "Expected", "Actual")
+
+	hasDiffs := false
+
+	for _, r := range drift.Resources {
+		for _, pd := range r.PropertyDifferences {
+			hasDiffs = true
+
+			diffs.Row(r.LogicalResourceID, pd.PropertyPath, pd.DifferenceType, pd.ExpectedValue, pd.ActualValue)
+		}
+	}
+
+	if hasDiffs {
+		sa.cli.Print(diffs.Render())
+	}
+}
+
+func (sa SA) colorizedDriftStatus(status string) string {
+	switch status {
+	case cloudformation.StackResourceDriftStatusInSync:
+		return sa.cli.Color.Success(status)
+	case cloudformation.StackResourceDriftStatusModified,
+		cloudformation.StackResourceDriftStatusDeleted,
+		cloudformation.StackDriftStatusDrifted:
+		return sa.cli.Color.Fail(status)
+	}
+
+	return sa.cli.Color.Neutral(status)
+}
+
+// checkDrift fails if the stack has drifted from its template.
+func (a *syncAction) checkDrift(stackCfg conf.Config, logger *cli.Logger) error {
+	drift, found, err := a.sa.detectStackDrift(a.ctx, stackCfg.Stack(), logger)
+	if err != nil || !found || !drift.Drifted() {
+		return err
+	}
+
+	a.promptMu.Lock()
+	a.sa.showDrift(drift)
+	a.promptMu.Unlock()
+
+	return fmt.Errorf("stack %s has drifted from its template", stackCfg.Name)
+}
@@ -0,0 +1,104 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// ExecuteChangeSets executes the change sets kept by the synchronization in
+// no-execute mode. The change sets are looked up by SyncOpts.ChangeSetName and
+// executed in the order of the stack dependencies. The stacks having no such
+// change set are skipped.
+func (sa SA) ExecuteChangeSets(ctx context.Context, cfg conf.Config, opts SyncOpts) error {
+	action := &syncAction{ctx: ctx, sa: sa, opts: opts, sched: newScheduler(1)}
+
+	return action.executeRecursively(cfg, []string{}, action.keptChangeSet)
+}
+
+// changeSetLookup returns the change set to be executed for the stack together
+// with the config the change set is executed with. The stack is skipped if the
+// returned change set is nil.
+type changeSetLookup func(stackCfg conf.Config, ids []string) (conf.Config, *awscf.ChangeSetHandle, error)
+
+func (a *syncAction) executeRecursively(stackCfg conf.Config, ids []string, lookup changeSetLookup) error {
+	if err := a.sa.runGroupHooks(stackCfg, stackCfg.Hooks.Pre); err != nil {
+		return err
+	}
+
+	if stackCfg.Body != "" {
+		if err := a.executeStack(stackCfg, ids, lookup); err != nil {
+			return err
+		}
+	}
+
+	nestedIds, err := stackCfg.StackIDsSortedByExecOrder()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range nestedIds {
+		if err := a.executeRecursively(stackCfg.Stacks[id], appendID(ids, id), lookup); err != nil {
+			return err
+		}
+	}
+
+	return a.sa.runGroupHooks(stackCfg, stackCfg.Hooks.Post)
+}
+
+func (a *syncAction) executeStack(stackCfg conf.Config, ids []string, lookup changeSetLookup) error {
+	if a.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	logger := a.sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name))
+
+	stackCfg, chSet, err := lookup(stackCfg, ids)
+	if err != nil || chSet == nil {
+		return err
+	}
+
+	stack := stackCfg.Stack()
+
+	a.sa.showChanges(chSet.Changes)
+
+	if !a.opts.NonInteractive {
+		// the change set to be executed is reviewed rather than the one the
+		// current config would produce
+		registered, err := stack.RegisteredChangeSet(chSet)
+		if err != nil {
+			return err
+		}
+
+		if err := a.sa.letUserChooseNextAction(registered, chSet.Changes); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("Executing change set %s", chSet.ConsoleURL())
+
+	if err := a.execChangeSet(stackCfg, stack, chSet, logger); err != nil {
+		return err
+	}
+
+	return a.protect(stackCfg, stack, logger)
+}
+
+func (a *syncAction) keptChangeSet(stackCfg conf.Config, _ []string) (conf.Config, *awscf.ChangeSetHandle, error) {
+	stackCfg, err := stackCfg.ResolveStackOutputs()
+	if err != nil {
+		return stackCfg, nil, err
+	}
+
+	chSet, err := stackCfg.Stack().LoadNamedChangeSet(a.opts.changeSetName())
+	if err == awscf.ErrChangeSetNotFound {
+		a.sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name)).
+			Infof("Change set %s is not found. Skipping", a.opts.changeSetName())
+
+		return stackCfg, nil, nil
+	}
+
+	return stackCfg, chSet, err
+}
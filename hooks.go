@@ -0,0 +1,97 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// runHooks executes the hooks streaming their output through the logger.
+func runHooks(hooks conf.HookCmds, env map[string]string, logger *cli.Logger) error {
+	return runHooksWithInput(hooks, env, nil, logger)
+}
+
+func runHooksWithInput(hooks conf.HookCmds, env map[string]string, input []byte, logger *cli.Logger) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	w := logger.LineWriter()
+	defer w.Flush()
+
+	return hooks.ExecWithInput(env, input, w)
+}
+
+// runGroupHooks executes the hooks wrapping the stack together with its nested
+// stacks.
+func (sa SA) runGroupHooks(cfg conf.Config, hooks conf.HookCmds) error {
+	prefix := ""
+	if cfg.Name != "" {
+		prefix = fmt.Sprintf("[%s] ", cfg.Name)
+	}
+
+	return runHooks(hooks, cfg.HookEnv(conf.HookContext{}), sa.cli.PrefixedLogger(prefix))
+}
+
+// runStackHooks executes the hooks of the stack operation. If info is not nil,
+// the outputs of the stack are passed to the hooks. They are requested only if
+// there are hooks to be executed.
+func runStackHooks(
+	cfg conf.Config,
+	hooks conf.HookCmds,
+	hc conf.HookContext,
+	info func() (awscf.StackInfo, error),
+	logger *cli.Logger,
+) error {
+	if len(hooks) == 0 {
+		return nil
+	}
+
+	if info != nil {
+		si, err := info()
+		if err != nil {
+			return err
+		}
+
+		hc.Outputs = si.Outputs()
+	}
+
+	return runHooks(hooks, cfg.HookEnv(hc), logger)
+}
+
+// runFailureHooks executes the hooks notifying about the failed execution of
+// the change set. The failures of the hooks are only logged, the failure of
+// the stack is what's reported to the user.
+func runFailureHooks(cfg conf.Config, stack *awscf.Stack, hc conf.HookContext, err error, logger *cli.Logger) {
+	if len(cfg.Hooks.OnFailure) == 0 && len(cfg.Hooks.OnRollback) == 0 {
+		return
+	}
+
+	hc.Err = err
+	hc.Events = stack.EventsTrack().Collected()
+
+	// the stack might be already deleted (e.g. if it's created with
+	// onFailure: DELETE)
+	info, ierr := stack.FreshInfo()
+	if ierr != nil {
+		logger.Warnf("Error while requesting the status of the stack: %s", ierr.Error())
+	} else {
+		hc.Status = info.Status()
+	}
+
+	run := func(hooks conf.HookCmds) {
+		herr := runHooksWithInput(hooks, cfg.HookEnv(hc), cfg.HookInput(hc), logger)
+		if herr != nil {
+			logger.Warnf("Error while executing hooks: %s", herr.Error())
+		}
+	}
+
+	run(cfg.Hooks.OnFailure)
+
+	if strings.Contains(hc.Status, "ROLLBACK") {
+		run(cfg.Hooks.OnRollback)
+	}
+}
@@ -0,0 +1,33 @@
+package assembly
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// Import imports the existing resources listed in the import section of the
+// stack config into the stack.
+func (sa SA) Import(ctx context.Context, stackCfg conf.Config, opts SyncOpts) error {
+	if len(stackCfg.Import) == 0 {
+		return fmt.Errorf("stack %s has no resources to import", stackCfg.Name)
+	}
+
+	action := &syncAction{ctx: ctx, sa: sa, opts: opts, sched: newScheduler(1)}
+
+	logger := sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name))
+	logger.Info("Importing resources")
+
+	stackCfg, err := stackCfg.ResolveStackOutputs()
+	if err != nil {
+		return err
+	}
+
+	stack, _, err := action.exec(stackCfg, stackCfg.ImportChangeSet(), logger)
+	if err != nil {
+		return err
+	}
+
+	return action.protect(stackCfg, stack, logger)
+}
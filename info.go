@@ -24,7 +24,12 @@ func (sa SA) colorizedStatus(status string) string {
 }
 
 func (sa SA) sprintEvent(e awscf.StackEvent) string {
-	return fmt.Sprintf("%s\t%s\t%s\t%s", e.ResourceType, sa.colorizedStatus(e.Status), e.LogicalResourceID, e.StatusReason)
+	resourceID := e.LogicalResourceID
+	if e.NestedStack != "" {
+		resourceID = e.NestedStack + "/" + resourceID
+	}
+
+	return fmt.Sprintf("%s\t%s\t%s\t%s", e.ResourceType, sa.colorizedStatus(e.Status), resourceID, e.StatusReason)
 }
 
 func (sa SA) Info(stack *awscf.Stack) error {
@@ -0,0 +1,106 @@
+package assembly
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+)
+
+// ErrInterrupted is returned when the operation is interrupted by the user.
+var ErrInterrupted = errors.New("interrupted")
+
+// WithInterruption returns the context canceled by the returned interrupt
+// function. The running operations are stopped gracefully when the context is
+// canceled. terminate tells that the process is terminated rather than
+// interrupted by the user (e.g. by SIGTERM). Nobody is asked then whether to
+// cancel the stack operations in progress, so they are left running.
+func WithInterruption(parent context.Context) (ctx context.Context, interrupt func(terminate bool)) {
+	in := &interruption{}
+	ctx, cancel := context.WithCancel(context.WithValue(parent, interruptionKey{}, in))
+
+	return ctx, func(terminate bool) {
+		if terminate {
+			atomic.StoreInt32(&in.terminated, 1)
+		}
+
+		cancel()
+	}
+}
+
+type interruptionKey struct{}
+
+type interruption struct {
+	terminated int32
+}
+
+func terminated(ctx context.Context) bool {
+	in, ok := ctx.Value(interruptionKey{}).(*interruption)
+	return ok && atomic.LoadInt32(&in.terminated) == 1
+}
+
+// interrupt is called when the context is canceled while the change set is
+// being executed. The user is offered to cancel the update of the stack. In
+// any case the stack operation is waited for so that the stack isn't left in
+// progress unnoticed. In non-interactive mode or if the process is terminated
+// nobody can be asked, so the stack operation is neither canceled nor waited
+// for.
+func (a *syncAction) interrupt(stack *awscf.Stack, chSet *awscf.ChangeSetHandle, logger *cli.Logger) error {
+	if a.opts.NonInteractive || terminated(a.ctx) {
+		logger.Warn("Stopped waiting for the stack operation. Cloudformation keeps performing it")
+		return ErrInterrupted
+	}
+
+	switch {
+	case chSet.IsImport:
+		logger.Warn("Resource import can't be canceled. Waiting for it to complete")
+	case !chSet.IsUpdate:
+		logger.Warn("Stack creation can't be canceled. Waiting for it to complete")
+	case a.confirmCancel(logger):
+		if err := stack.CancelUpdate(); err != nil {
+			logger.Warnf("Failed to cancel the update: %s", err)
+			logger.Warn("Waiting for the update to complete")
+		} else {
+			logger.Warn("Update is canceled. Waiting for the rollback to complete")
+		}
+	default:
+		logger.Info("Waiting for the update to complete")
+	}
+
+	// the context is canceled already. The second interrupt terminates the
+	// process right away
+	if err := chSet.Wait(context.Background()); err != nil {
+		logger.Warnf("Stack operation is not successful: %s", err)
+	}
+
+	return ErrInterrupted
+}
+
+func (a *syncAction) confirmCancel(logger *cli.Logger) bool {
+	a.cancelOnce.Do(func() {
+		a.cancelConfirmed = a.askCancel(logger)
+	})
+
+	return a.cancelConfirmed
+}
+
+func (a *syncAction) askCancel(logger *cli.Logger) bool {
+	// the prompt of another stack might be reading the input. The question is
+	// asked once that prompt is answered
+	a.promptMu.Lock()
+	defer a.promptMu.Unlock()
+
+	logger.Warn("Synchronization is interrupted")
+
+	response, err := a.sa.cli.Ask("Cancel the updates in progress and roll the stacks back? [Y/n] ")
+	if err != nil {
+		return false
+	}
+
+	response = strings.ToLower(strings.TrimSpace(response))
+
+	return response == "" || response == "y" || response == "yes"
+}
@@ -0,0 +1,85 @@
+package assembly
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerminationIsToldApartFromInterruption(t *testing.T) {
+	ctx, interrupt := WithInterruption(context.Background())
+	interrupt(false)
+
+	assert.Error(t, ctx.Err())
+	assert.False(t, terminated(ctx))
+
+	ctx, interrupt = WithInterruption(context.Background())
+	interrupt(true)
+
+	assert.Error(t, ctx.Err())
+	assert.True(t, terminated(ctx))
+}
+
+func TestUpdateIsLeftRunningIfNobodyCanBeAsked(t *testing.T) {
+	terminatedCtx, terminate := WithInterruption(context.Background())
+	terminate(true)
+
+	interruptedCtx, interrupt := WithInterruption(context.Background())
+	interrupt(false)
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		opts SyncOpts
+	}{
+		{"non-interactive", interruptedCtx, SyncOpts{NonInteractive: true}},
+		{"terminated", terminatedCtx, SyncOpts{}},
+	}
+
+	for _, c := range cases {
+		sa, out := testSA()
+		action := &syncAction{ctx: c.ctx, sa: sa, opts: c.opts}
+
+		// any call of aws panics, i.e. the update is neither canceled nor
+		// waited for
+		stack := awscf.NewStack("stas-app", &untouchedCF{}, nil)
+
+		err := action.interrupt(stack, &awscf.ChangeSetHandle{IsUpdate: true}, sa.cli.PrefixedLogger(""))
+
+		assert.Equal(t, ErrInterrupted, err, c.name)
+		assert.Contains(t, out.String(), "Stopped waiting for the stack operation", c.name)
+	}
+}
+
+func TestCancellationIsAskedOnceOtherPromptIsAnswered(t *testing.T) {
+	sa, out := testSA()
+	sa.cli.Reader = strings.NewReader("n\n")
+	action := &syncAction{ctx: context.Background(), sa: sa}
+
+	action.promptMu.Lock()
+
+	confirmed := make(chan bool)
+	go func() {
+		confirmed <- action.confirmCancel(sa.cli.PrefixedLogger(""))
+	}()
+
+	select {
+	case <-confirmed:
+		t.Fatal("cancellation is confirmed while another prompt is waiting for input")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	action.promptMu.Unlock()
+
+	assert.False(t, <-confirmed)
+	assert.Contains(t, out.String(), "Cancel the updates in progress")
+}
+
+type untouchedCF struct {
+	cloudformationiface.CloudFormationAPI
+}
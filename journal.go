@@ -0,0 +1,95 @@
+package assembly
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// journal keeps track of the stacks that are synchronized successfully. It is
+// saved after every synchronized stack so that the failed synchronization can
+// be resumed from the point of failure.
+type journal struct {
+	path string
+	mu   sync.Mutex
+
+	// Stacks maps the path of IDs of the synchronized stack to the hash of
+	// its config
+	Stacks map[string]string
+}
+
+func newJournal(path string) *journal {
+	return &journal{path: path, Stacks: map[string]string{}}
+}
+
+// loadJournal loads the journal from the file. If the file doesn't exist, an
+// empty journal is returned.
+func loadJournal(path string) (*journal, error) {
+	j := newJournal(path)
+
+	buf, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return j, nil
+	}
+
+	if err != nil {
+		return j, err
+	}
+
+	if err := json.Unmarshal(buf, j); err != nil {
+		return j, err
+	}
+
+	if j.Stacks == nil {
+		j.Stacks = map[string]string{}
+	}
+
+	return j, nil
+}
+
+// isComplete returns true if the stack is recorded in the journal and its
+// config hasn't changed since then.
+func (j *journal) isComplete(ids []string, hash string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	h, ok := j.Stacks[journalKey(ids)]
+
+	return ok && h == hash
+}
+
+func (j *journal) record(ids []string, hash string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.Stacks[journalKey(ids)] = hash
+
+	buf, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(j.path, buf, 0600)
+}
+
+func (j *journal) remove() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func journalKey(ids []string) string {
+	return strings.Join(ids, "/")
+}
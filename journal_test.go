@@ -0,0 +1,207 @@
+package assembly
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/cloudformation/cloudformationiface"
+	"github.com/molecule-man/stack-assembly/aws"
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournalRecordsCompletedStacks(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	j := newJournal(path)
+	require.NoError(t, j.record([]string{"app", "db"}, "hash"))
+
+	assert.True(t, j.isComplete([]string{"app", "db"}, "hash"))
+	assert.False(t, j.isComplete([]string{"app", "db"}, "changed"), "config of the stack has changed")
+	assert.False(t, j.isComplete([]string{"app"}, "hash"), "stack is not recorded")
+}
+
+func TestJournalIsResumedFromFile(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	require.NoError(t, newJournal(path).record([]string{"app", "db"}, "hash"))
+
+	j, err := loadJournal(path)
+	require.NoError(t, err)
+
+	assert.True(t, j.isComplete([]string{"app", "db"}, "hash"))
+	assert.False(t, j.isComplete([]string{"app", "web"}, "hash"))
+}
+
+func TestMissingJournalIsEmpty(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	j, err := loadJournal(path)
+	require.NoError(t, err)
+
+	assert.Empty(t, j.Stacks)
+}
+
+func TestRemovedJournalIsNotResumed(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	j := newJournal(path)
+	require.NoError(t, j.record([]string{"app"}, "hash"))
+	require.NoError(t, j.remove())
+	require.NoError(t, j.remove(), "removal of the missing journal is not an error")
+
+	j, err := loadJournal(path)
+	require.NoError(t, err)
+
+	assert.False(t, j.isComplete([]string{"app"}, "hash"))
+}
+
+func TestResumeRequiresJournal(t *testing.T) {
+	_, err := SA{}.Sync(context.Background(), conf.Config{}, SyncOpts{Resume: true})
+
+	assert.EqualError(t, err, "journal path is required to resume the synchronization")
+}
+
+func TestResumeSkipsStacksSynchronizedInPreviousRun(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	cf := &unreachableCF{}
+	cfg := dependentStacksConfig(t, cf)
+
+	j := newJournal(path)
+	require.NoError(t, j.record([]string{"db"}, stackHash(t, cfg.Stacks["db"])))
+	require.NoError(t, j.record([]string{"app"}, stackHash(t, cfg.Stacks["app"])))
+
+	sa, out := testSA()
+	_, err := sa.Sync(context.Background(), cfg, SyncOpts{NonInteractive: true, Resume: true, JournalPath: path})
+	require.NoError(t, err)
+
+	assert.Zero(t, cf.calls, "synchronized stacks are not requested from aws")
+	assert.Contains(t, out.String(), "[stas-db] Stack is synchronized in the previous run and is unchanged since then. Skipping")
+	assert.Contains(t, out.String(), "[stas-app] Stack is synchronized in the previous run and is unchanged since then. Skipping")
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "journal of the completed synchronization is removed")
+}
+
+func TestResumeSynchronizesStacksChangedSincePreviousRun(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	cf := &unreachableCF{}
+	cfg := dependentStacksConfig(t, cf)
+	dbHash := stackHash(t, cfg.Stacks["db"])
+
+	j := newJournal(path)
+	require.NoError(t, j.record([]string{"db"}, dbHash))
+	require.NoError(t, j.record([]string{"app"}, "hash of the previous config"))
+
+	sa, out := testSA()
+	_, err := sa.Sync(context.Background(), cfg, SyncOpts{NonInteractive: true, Resume: true, JournalPath: path})
+
+	assert.Equal(t, errUnreachable, err)
+	assert.NotZero(t, cf.calls)
+	assert.Contains(t, out.String(), "[stas-db] Stack is synchronized in the previous run and is unchanged since then. Skipping")
+	assert.NotContains(t, out.String(), "[stas-app] Stack is synchronized in the previous run")
+
+	j, err = loadJournal(path)
+	require.NoError(t, err)
+	assert.Equal(t, dbHash, j.Stacks["db"], "journal is kept for the next resume")
+}
+
+func TestPreviousJournalIsDiscardedWithoutResume(t *testing.T) {
+	path, cleanup := journalPath(t)
+	defer cleanup()
+
+	cf := &unreachableCF{}
+	cfg := dependentStacksConfig(t, cf)
+
+	require.NoError(t, newJournal(path).record([]string{"db"}, stackHash(t, cfg.Stacks["db"])))
+
+	sa, out := testSA()
+	_, err := sa.Sync(context.Background(), cfg, SyncOpts{NonInteractive: true, JournalPath: path})
+
+	assert.Equal(t, errUnreachable, err)
+	assert.NotContains(t, out.String(), "Skipping")
+
+	j, err := loadJournal(path)
+	require.NoError(t, err)
+	assert.Empty(t, j.Stacks)
+}
+
+var errUnreachable = errors.New("aws is unreachable")
+
+// unreachableCF fails the requests the synchronization of a stack starts
+// with. Any other request panics.
+type unreachableCF struct {
+	cloudformationiface.CloudFormationAPI
+	calls int
+}
+
+func (cf *unreachableCF) ValidateTemplate(*cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error) {
+	cf.calls++
+	return nil, errUnreachable
+}
+
+func (cf *unreachableCF) DescribeStacks(*cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	cf.calls++
+	return nil, errUnreachable
+}
+
+type fakeAwsProv struct {
+	cf cloudformationiface.CloudFormationAPI
+}
+
+func (p fakeAwsProv) Must(cfg aws.Config) *aws.AWS {
+	return &aws.AWS{CF: p.cf}
+}
+
+func (p fakeAwsProv) New(cfg aws.Config) (*aws.AWS, error) {
+	return p.Must(cfg), nil
+}
+
+// dependentStacksConfig returns the config of the app stack depending on the db stack
+func dependentStacksConfig(t *testing.T, cf cloudformationiface.CloudFormationAPI) conf.Config {
+	cfg := conf.Config{Stacks: map[string]conf.Config{
+		"db":  {Name: "stas-db", Body: "Resources: {}"},
+		"app": {Name: "stas-app", Body: "Resources: {}", DependsOn: []string{"db"}},
+	}}
+
+	require.NoError(t, conf.NewLoader(&conf.OsFS{}, fakeAwsProv{cf}).InitConfig(&cfg))
+
+	return cfg
+}
+
+func stackHash(t *testing.T, cfg conf.Config) string {
+	h, err := cfg.Hash()
+	require.NoError(t, err)
+
+	return h
+}
+
+func testSA() (SA, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+
+	return SA{&cli.CLI{Writer: out, Errorer: out, Color: cli.Color{Disabled: true}}}, out
+}
+
+// journalPath returns the path of the journal in a not yet existing directory
+func journalPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "stas-journal")
+	require.NoError(t, err)
+
+	return filepath.Join(dir, "nested", "journal.json"), func() { os.RemoveAll(dir) }
+}
@@ -0,0 +1,70 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// ExplainOrder prints the order in which the stacks are synchronized together
+// with the dependencies that cause this order.
+func (sa SA) ExplainOrder(cfg conf.Config) error {
+	return sa.explainOrder(cfg, []string{})
+}
+
+func (sa SA) explainOrder(cfg conf.Config, path []string) error {
+	if len(cfg.Stacks) == 0 {
+		return nil
+	}
+
+	ids, err := cfg.StackIDsSortedByExecOrder()
+	if err != nil {
+		return err
+	}
+
+	deps := map[string][]string{}
+
+	for _, d := range cfg.Dependencies() {
+		origin := "explicit"
+		if d.Inferred {
+			origin = "inferred"
+		}
+
+		deps[d.ID] = append(deps[d.ID], fmt.Sprintf("%s (%s: %s)", d.DependsOn, origin, d.Reason))
+	}
+
+	group := "root"
+	if len(path) > 0 {
+		group = strings.Join(path, " ")
+	}
+
+	sa.cli.Print(sa.cli.Color.Neutral(fmt.Sprintf("Order of stacks under %s:", group)))
+
+	w := cli.NewColWriter(sa.cli.Writer, " ")
+
+	for i, id := range ids {
+		line := fmt.Sprintf("  %d.\t%s", i+1, id)
+
+		if len(deps[id]) > 0 {
+			line += "\t<- " + strings.Join(deps[id], ", ")
+		}
+
+		fmt.Fprintln(w, line)
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	sa.cli.Print("")
+
+	for _, id := range ids {
+		if err := sa.explainOrder(cfg.Stacks[id], appendID(path, id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,355 @@
+package assembly
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// Plan is a set of change sets that are registered but not executed yet. The
+// change sets of the plan are executed by Apply.
+type Plan struct {
+	Stacks []PlannedStack
+}
+
+// PlannedStack describes the change set registered for a stack and the state
+// of the stack at the moment of registration.
+type PlannedStack struct {
+	// IDs is the path of IDs leading to the stack config.
+	IDs  []string
+	Name string
+
+	ChangeSetID  string
+	IsUpdate     bool
+	TemplateHash string
+	Parameters   map[string]string
+
+	StackID         string
+	StackStatus     string
+	LastUpdatedTime time.Time
+}
+
+// Plan registers change sets for all the stacks of the config without
+// executing them. If planning of any stack fails, the change sets registered
+// for the other stacks are deleted.
+func (sa SA) Plan(ctx context.Context, cfg conf.Config, opts SyncOpts) (Plan, error) {
+	if opts.Concurrency > 1 {
+		sa = sa.withLockedOutput()
+	}
+
+	action := &syncAction{ctx: ctx, sa: sa, opts: opts, sched: newScheduler(opts.Concurrency)}
+	p := &planning{}
+
+	err := action.planRecursively(cfg, []string{}, p)
+	if err != nil {
+		p.cleanup(sa.cli.PrefixedLogger(""))
+		return Plan{Stacks: []PlannedStack{}}, err
+	}
+
+	return Plan{Stacks: p.stacks}, nil
+}
+
+// Apply executes the change sets of the plan. Nothing is executed if any of
+// the planned stacks has changed since the plan was made. The change sets are
+// executed in the order of the stack dependencies, the stacks missing in the
+// plan are skipped.
+func (sa SA) Apply(ctx context.Context, cfg conf.Config, plan Plan, opts SyncOpts) error {
+	action := &syncAction{ctx: ctx, sa: sa, opts: opts, sched: newScheduler(1)}
+
+	verified := make(verifiedPlan, len(plan.Stacks))
+
+	for _, ps := range plan.Stacks {
+		stackCfg, err := findStackConfig(cfg, ps.IDs)
+		if err != nil {
+			return err
+		}
+
+		stackCfg, chSet, err := verifyPlannedStack(stackCfg, ps)
+		if err != nil {
+			return fmt.Errorf("plan can't be applied to the stack %s: %w", ps.Name, err)
+		}
+
+		verified[strings.Join(ps.IDs, ".")] = verifiedStack{cfg: stackCfg, chSet: chSet}
+	}
+
+	return action.executeRecursively(cfg, []string{}, verified.changeSet)
+}
+
+// verifiedPlan maps the IDs of the planned stacks (joined by dots) to the
+// verified stack configs and their change sets.
+type verifiedPlan map[string]verifiedStack
+
+type verifiedStack struct {
+	cfg   conf.Config
+	chSet *awscf.ChangeSetHandle
+}
+
+func (p verifiedPlan) changeSet(stackCfg conf.Config, ids []string) (conf.Config, *awscf.ChangeSetHandle, error) {
+	v, ok := p[strings.Join(ids, ".")]
+	if !ok {
+		return stackCfg, nil, nil
+	}
+
+	return v.cfg, v.chSet, nil
+}
+
+// planning collects the planned stacks together with their change sets, so
+// that the change sets can be deleted if the plan can't be made.
+type planning struct {
+	mu     sync.Mutex
+	stacks []PlannedStack
+	chSets []*awscf.ChangeSetHandle
+}
+
+func (p *planning) register(chSet *awscf.ChangeSetHandle) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.chSets = append(p.chSets, chSet)
+}
+
+func (p *planning) add(ps PlannedStack) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stacks = append(p.stacks, ps)
+}
+
+func (p *planning) stackNames() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, len(p.stacks))
+	for i, s := range p.stacks {
+		names[i] = s.Name
+	}
+
+	return names
+}
+
+func (p *planning) cleanup(logger *cli.Logger) {
+	for _, chSet := range p.chSets {
+		logger.Infof("Deleting change set %s", chSet.ID)
+
+		if err := chSet.Delete(); err != nil {
+			logger.Warnf("Error while deleting change set: %s", err.Error())
+		}
+	}
+}
+
+func (a *syncAction) planRecursively(stackCfg conf.Config, ids []string, p *planning) error {
+	if stackCfg.Body != "" {
+		err := a.sched.run(func() error {
+			return a.planStack(stackCfg, ids, p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if a.opts.Concurrency <= 1 {
+		nestedIds, err := stackCfg.StackIDsSortedByExecOrder()
+		if err != nil {
+			return err
+		}
+
+		for _, id := range nestedIds {
+			if err := a.planRecursively(stackCfg.Stacks[id], appendID(ids, id), p); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	levels, err := stackCfg.StackIDsByExecLevel()
+	if err != nil {
+		return err
+	}
+
+	// the stacks of the level are added to the plan in the order of
+	// completion. They don't depend on each other, so any order is fine
+	for _, level := range levels {
+		level := level
+
+		err := a.sched.each(len(level), func(i int) error {
+			return a.planRecursively(stackCfg.Stacks[level[i]], appendID(ids, level[i]), p)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *syncAction) planStack(stackCfg conf.Config, ids []string, p *planning) error {
+	if a.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	hash, err := templateHash(stackCfg)
+	if err != nil {
+		return err
+	}
+
+	ps := PlannedStack{IDs: ids, Name: stackCfg.Name, TemplateHash: hash}
+
+	logger := a.sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name))
+	logger.Info("Planning template")
+
+	if err = checkPendingOutputs(stackCfg, p.stackNames()); err != nil {
+		return err
+	}
+
+	stackCfg, err = stackCfg.ResolveStackOutputs()
+	if err != nil {
+		return err
+	}
+
+	cs := stackCfg.ChangeSet()
+
+	defer func() {
+		if closeErr := cs.Close(); closeErr != nil {
+			logger.Warnf("Error while cleaning up: %s", closeErr.Error())
+		}
+	}()
+
+	chSet, err := a.register(cs, logger)
+
+	if err == awscf.ErrNoChange {
+		logger.Info("No changes to be synchronized")
+
+		// the failed change set isn't a part of the plan
+		if derr := chSet.Delete(); derr != nil {
+			logger.Warnf("Error while deleting change set: %s", derr.Error())
+		}
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	p.register(chSet)
+
+	a.promptMu.Lock()
+	logger.Infof("Change set is created: %s", chSet.ID)
+	a.sa.showChanges(chSet.Changes)
+	a.promptMu.Unlock()
+
+	ps.ChangeSetID = chSet.ID
+	ps.IsUpdate = chSet.IsUpdate
+	ps.Parameters = make(map[string]string, len(chSet.Parameters))
+
+	for _, param := range chSet.Parameters {
+		ps.Parameters[param.Key] = param.Val
+	}
+
+	// the stack is described again since the registration of the change set
+	// might have created it
+	info, err := cs.Stack().FreshInfo()
+	if err != nil {
+		return err
+	}
+
+	ps.StackID = info.ID()
+	ps.StackStatus = info.Status()
+	ps.LastUpdatedTime = info.LastUpdatedTime()
+
+	p.add(ps)
+
+	return nil
+}
+
+// noEchoValue is what cloudformation returns instead of the values of the
+// parameters declared with NoEcho.
+const noEchoValue = "****"
+
+// verifyPlannedStack checks that neither the stack nor its config has changed
+// since the plan was made. The config with the resolved stack outputs is
+// returned together with the planned change set.
+func verifyPlannedStack(stackCfg conf.Config, ps PlannedStack) (conf.Config, *awscf.ChangeSetHandle, error) {
+	if stackCfg.Name != ps.Name {
+		return stackCfg, nil, fmt.Errorf("stack name has changed to %s", stackCfg.Name)
+	}
+
+	hash, err := templateHash(stackCfg)
+	if err != nil {
+		return stackCfg, nil, err
+	}
+
+	if hash != ps.TemplateHash {
+		return stackCfg, nil, errors.New("template has changed since the plan was made")
+	}
+
+	stackCfg, err = stackCfg.ResolveStackOutputs()
+	if err != nil {
+		return stackCfg, nil, err
+	}
+
+	for k, v := range stackCfg.Parameters {
+		planned, ok := ps.Parameters[k]
+		if ok && planned != v && planned != noEchoValue {
+			return stackCfg, nil, fmt.Errorf("parameter %s has changed from %s to %s", k, planned, v)
+		}
+	}
+
+	stack := stackCfg.Stack()
+
+	info, err := stack.Info()
+	if err != nil {
+		return stackCfg, nil, err
+	}
+
+	switch {
+	case info.ID() != ps.StackID:
+		return stackCfg, nil, errors.New("stack has been recreated since the plan was made")
+	case info.Status() != ps.StackStatus:
+		return stackCfg, nil, fmt.Errorf("stack status has changed from %s to %s", ps.StackStatus, info.Status())
+	case !info.LastUpdatedTime().Equal(ps.LastUpdatedTime):
+		return stackCfg, nil, fmt.Errorf("stack has been updated at %s", info.LastUpdatedTime().Format(time.RFC3339))
+	}
+
+	chSet, err := stack.LoadChangeSet(ps.ChangeSetID, ps.IsUpdate)
+
+	return stackCfg, chSet, err
+}
+
+func findStackConfig(cfg conf.Config, ids []string) (conf.Config, error) {
+	for i, id := range ids {
+		stackCfg, ok := cfg.Stacks[id]
+		if !ok {
+			return cfg, fmt.Errorf("stack %s is not found in the config", strings.Join(ids[:i+1], "."))
+		}
+
+		cfg = stackCfg
+	}
+
+	return cfg, nil
+}
+
+// templateHash is the hash of the template of the stack. The template referred
+// by the url is downloaded, so that the change of its contents is detected.
+func templateHash(stackCfg conf.Config) (string, error) {
+	body, err := stackCfg.ChangeSet().TemplateBody()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(stackCfg.URL))
+	h.Write([]byte(body))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
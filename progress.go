@@ -0,0 +1,256 @@
+package assembly
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+)
+
+const (
+	// progressTick is the interval between the redraws of the live view
+	progressTick = 250 * time.Millisecond
+)
+
+// maxReasonLen limits the length of the status reason in the live view so
+// that the rows aren't wrapped (which would break the redrawing)
+const maxReasonLen = 60
+
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// watch shows the progress of the change set execution until the returned
+// channel is signaled (see stopWatching). The live view is used if the output
+// is a terminal and the stacks are synchronized one by one. Otherwise the
+// events are shown line by line.
+func (a *syncAction) watch(stack *awscf.Stack, chSet *awscf.ChangeSetHandle) (chan bool, bool) {
+	logger := a.sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stack.Name))
+
+	if !a.sa.cli.Terminal || a.opts.Concurrency > 1 {
+		return a.sa.showEvents(stack, logger), false
+	}
+
+	return a.sa.showProgress(stack, chSet.Changes, logger), true
+}
+
+func stopWatching(wait chan bool) {
+	wait <- true
+	<-wait
+}
+
+// showProgress shows a live view with one row per changed resource.
+func (sa SA) showProgress(stack *awscf.Stack, changes []awscf.Change, logger *cli.Logger) chan bool {
+	wait := make(chan bool)
+
+	if _, err := stack.EventsTrack().FreshEvents(); err != nil {
+		logger.Warnf("got an error while requesting stack events: %s", err)
+	}
+
+	view := newProgressView(sa, stack.Name, changes)
+
+	go func() {
+		block := cli.NewLiveBlock(sa.cli.Writer)
+		polled := time.Time{}
+
+		poll := func(now time.Time) {
+			events, err := stack.EventsTrack().FreshEvents()
+			if err != nil {
+				logger.Warnf("got an error while requesting stack events: %s", err)
+
+				// the warning has moved the cursor, the view starts anew
+				block = cli.NewLiveBlock(sa.cli.Writer)
+			}
+
+			for _, e := range events.Reversed() {
+				view.apply(e, now)
+			}
+
+			polled = now
+		}
+
+		for {
+			now := time.Now()
+
+			if now.Sub(polled) >= stack.PollInterval() {
+				poll(now)
+			}
+
+			_ = block.Update(view.render(now))
+
+			select {
+			case <-wait:
+				poll(time.Now())
+				_ = block.Update(view.render(time.Now()))
+
+				wait <- true
+
+				return
+			case <-time.After(progressTick):
+			}
+		}
+	}()
+
+	return wait
+}
+
+type resourceProgress struct {
+	logicalID    string
+	resourceType string
+	status       string
+	reason       string
+	started      time.Time
+	finished     time.Time
+}
+
+func (p *resourceProgress) done() bool {
+	return p.status != "" && !strings.HasSuffix(p.status, "_IN_PROGRESS")
+}
+
+func (p *resourceProgress) elapsed(now time.Time) string {
+	switch {
+	case p.started.IsZero():
+		return ""
+	case p.done():
+		return p.finished.Sub(p.started).Round(time.Second).String()
+	}
+
+	return now.Sub(p.started).Round(time.Second).String()
+}
+
+type progressView struct {
+	sa        SA
+	stackName string
+	started   time.Time
+	frame     int
+
+	stack     resourceProgress
+	resources []*resourceProgress
+	byID      map[string]*resourceProgress
+}
+
+func newProgressView(sa SA, stackName string, changes []awscf.Change) *progressView {
+	v := &progressView{
+		sa:        sa,
+		stackName: stackName,
+		started:   time.Now(),
+		resources: make([]*resourceProgress, 0, len(changes)),
+		byID:      make(map[string]*resourceProgress, len(changes)),
+	}
+
+	for _, c := range changes {
+		v.add(c.LogicalResourceID, c.ResourceType)
+	}
+
+	return v
+}
+
+func (v *progressView) add(logicalID, resourceType string) *resourceProgress {
+	p := &resourceProgress{logicalID: logicalID, resourceType: resourceType}
+	v.resources = append(v.resources, p)
+	v.byID[logicalID] = p
+
+	return p
+}
+
+// apply updates the view with the event. The events have to be applied in
+// chronological order.
+func (v *progressView) apply(e awscf.StackEvent, now time.Time) {
+	if e.NestedStack != "" {
+		v.applyNested(e)
+		return
+	}
+
+	p := &v.stack
+
+	if e.LogicalResourceID != v.stackName {
+		var ok bool
+		if p, ok = v.byID[e.LogicalResourceID]; !ok {
+			// resource that is changed although it isn't in the change set,
+			// e.g. because of the dynamic references
+			p = v.add(e.LogicalResourceID, e.ResourceType)
+		}
+	}
+
+	if p.started.IsZero() {
+		p.started = now
+	}
+
+	p.status = e.Status
+	p.reason = e.StatusReason
+
+	if p.done() {
+		p.finished = now
+	}
+}
+
+// applyNested shows the failures inside the nested stack in the row of the
+// nested stack resource.
+func (v *progressView) applyNested(e awscf.StackEvent) {
+	if !strings.HasSuffix(e.Status, "_FAILED") {
+		return
+	}
+
+	nestedID := strings.SplitN(e.NestedStack, "/", 2)[0]
+
+	if p, ok := v.byID[nestedID]; ok && !p.done() {
+		p.reason = fmt.Sprintf("%s/%s: %s", e.NestedStack, e.LogicalResourceID, e.StatusReason)
+	}
+}
+
+func (v *progressView) render(now time.Time) string {
+	v.frame++
+
+	completed := 0
+
+	for _, p := range v.resources {
+		if p.done() {
+			completed++
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "[%s] %d/%d changes complete, %s elapsed", v.stackName, completed, len(v.resources),
+		now.Sub(v.started).Round(time.Second))
+
+	if v.stack.status != "" {
+		fmt.Fprintf(buf, ". Stack status: %s", v.sa.colorizedStatus(v.stack.status))
+	}
+
+	buf.WriteString("\n")
+
+	w := cli.NewColWriter(buf, " ")
+
+	for _, p := range v.resources {
+		mark := " "
+
+		switch {
+		case p.done():
+			mark = "*"
+		case !p.started.IsZero():
+			mark = spinnerFrames[v.frame%len(spinnerFrames)]
+		}
+
+		status := v.sa.cli.Color.Neutral("PENDING")
+		if p.status != "" {
+			status = v.sa.colorizedStatus(p.status)
+		}
+
+		fmt.Fprintf(w, "  %s %s\t%s\t%s\t%s\t%s\n",
+			mark, p.resourceType, p.logicalID, status, p.elapsed(now), truncate(p.reason, maxReasonLen))
+	}
+
+	_ = w.Flush()
+
+	return buf.String()
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+
+	return string(r[:max-3]) + "..."
+}
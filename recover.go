@@ -0,0 +1,201 @@
+package assembly
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// waiting for the busy stack might leave it in the state that needs to be
+// recovered as well, hence more than one recovery attempt is made
+const maxRecoveryAttempts = 2
+
+// registerRecovering registers the change set. If the stack is not in the
+// state in which it can be updated, the stack is recovered first.
+func (a *syncAction) registerRecovering(
+	stackCfg conf.Config,
+	cs *awscf.ChangeSet,
+	logger *cli.Logger,
+) (*awscf.ChangeSetHandle, error) {
+	chSet, err := a.register(cs, logger)
+
+	for i := 0; i < maxRecoveryAttempts; i++ {
+		serr, ok := err.(*awscf.StackStateError)
+		if !ok {
+			return chSet, err
+		}
+
+		if err := a.recoverStack(stackCfg, cs.Stack(), serr, logger); err != nil {
+			return chSet, err
+		}
+
+		chSet, err = a.register(cs, logger)
+	}
+
+	return chSet, err
+}
+
+// recoverStack brings the stack that can't be updated because of the failure
+// of the previous operation (or because of the operation in progress) into the
+// state in which it can be updated.
+func (a *syncAction) recoverStack(
+	stackCfg conf.Config,
+	stack *awscf.Stack,
+	serr *awscf.StackStateError,
+	logger *cli.Logger,
+) error {
+	if serr.InProgress() {
+		return a.waitUntilIdle(stack, serr, logger)
+	}
+
+	// recovery changes the stack, which is not allowed in no-execute mode
+	if a.opts.NoExecute {
+		return serr
+	}
+
+	switch serr.Status {
+	case cloudformation.StackStatusRollbackComplete:
+		return a.recreate(stackCfg, stack, serr, logger)
+	case cloudformation.StackStatusUpdateRollbackFailed:
+		return a.continueRollback(stack, serr, logger)
+	}
+
+	return serr
+}
+
+func (a *syncAction) waitUntilIdle(stack *awscf.Stack, serr *awscf.StackStateError, logger *cli.Logger) error {
+	if a.opts.OnBusy != OnBusyWait {
+		return fmt.Errorf("%w. Use --on-busy=%s to wait for the operation to complete", serr, OnBusyWait)
+	}
+
+	logger.Warnf("Another operation is in progress on the stack (status: %s). Waiting for it to complete", serr.Status)
+
+	wait := a.sa.showEvents(stack, logger)
+
+	err := stack.WaitUntilIdle(a.ctx)
+
+	wait <- true
+	<-wait
+
+	if a.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	return err
+}
+
+func (a *syncAction) recreate(stackCfg conf.Config, stack *awscf.Stack, serr *awscf.StackStateError, logger *cli.Logger) error {
+	if !stackCfg.RecreateOnRollbackComplete {
+		return fmt.Errorf("%w. Set recreateOnRollbackComplete in the stack config to let it be recreated automatically", serr)
+	}
+
+	logger.Warnf("Stack is in %s state. Deleting it in order to create it again", serr.Status)
+
+	err := stack.Delete(a.ctx)
+	if a.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Stack is deleted")
+
+	return nil
+}
+
+func (a *syncAction) continueRollback(stack *awscf.Stack, serr *awscf.StackStateError, logger *cli.Logger) error {
+	if a.opts.NonInteractive {
+		return serr
+	}
+
+	resourcesToSkip, err := a.askResourcesToSkip(serr, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Continuing update rollback")
+
+	wait := a.sa.showEvents(stack, logger)
+
+	err = stack.ContinueUpdateRollback(a.ctx, resourcesToSkip)
+
+	wait <- true
+	<-wait
+
+	if a.ctx.Err() != nil {
+		return ErrInterrupted
+	}
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Update rollback is complete")
+
+	return nil
+}
+
+func (a *syncAction) askResourcesToSkip(serr *awscf.StackStateError, logger *cli.Logger) ([]string, error) {
+	a.promptMu.Lock()
+	defer a.promptMu.Unlock()
+
+	logger.Warn(serr.Error())
+
+	var (
+		resourcesToSkip []string
+		actionErr       error
+	)
+
+	chosen := false
+
+	for !chosen && actionErr == nil {
+		err := a.sa.cli.Prompt([]cli.PromptCmd{
+			{
+				Description:   "[c]ontinue rollback",
+				TriggerInputs: []string{"c", "continue"},
+				Action: func() {
+					chosen = true
+				},
+			},
+			{
+				Description:   "[s]kip resources and continue rollback",
+				TriggerInputs: []string{"s", "skip"},
+				Action: func() {
+					response, rerr := a.sa.cli.Ask("Enter logical IDs of the resources to skip (comma separated): ")
+					if rerr != nil {
+						actionErr = rerr
+						return
+					}
+
+					for _, r := range strings.Split(response, ",") {
+						if r = strings.TrimSpace(r); r != "" {
+							resourcesToSkip = append(resourcesToSkip, r)
+						}
+					}
+
+					chosen = true
+				},
+			},
+			{
+				Description:   "[q]uit",
+				TriggerInputs: []string{"q", "quit"},
+				Action: func() {
+					a.sa.cli.Error("Interrupted by user")
+					actionErr = errors.New("sync is canceled")
+				},
+			},
+		})
+		if err != cli.ErrPromptCommandIsNotKnown {
+			MustSucceed(err)
+		}
+	}
+
+	return resourcesToSkip, actionErr
+}
@@ -9,3 +9,20 @@ type SA struct {
 func New(c *cli.CLI) *SA {
 	return &SA{c}
 }
+
+// withLockedOutput returns a copy of SA which output is safe to be written
+// from several goroutines.
+func (sa SA) withLockedOutput() SA {
+	c := *sa.cli
+	w := cli.NewLockedWriter(c.Writer)
+
+	if c.Errorer == c.Writer {
+		c.Errorer = w
+	} else {
+		c.Errorer = cli.NewLockedWriter(c.Errorer)
+	}
+
+	c.Writer = w
+
+	return SA{&c}
+}
@@ -0,0 +1,100 @@
+package assembly
+
+import (
+	"errors"
+	"sync"
+)
+
+var errNotScheduled = errors.New("not scheduled because of the failure of another stack")
+
+// scheduler limits the number of stack operations running at the same time
+// and stops starting new operations as soon as one of them fails (unless
+// keepGoing is set).
+type scheduler struct {
+	slots chan struct{}
+
+	// keepGoing makes the scheduler start new operations regardless of the
+	// failed ones
+	keepGoing bool
+
+	mu     sync.Mutex
+	failed bool
+}
+
+func newScheduler(concurrency int) *scheduler {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &scheduler{slots: make(chan struct{}, concurrency)}
+}
+
+// run waits for a free slot and executes fn in it. If any operation has
+// failed in the meantime, fn is not executed and errNotScheduled is returned.
+func (s *scheduler) run(fn func() error) error {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	if s.hasFailed() {
+		return errNotScheduled
+	}
+
+	err := fn()
+	if err != nil {
+		s.fail()
+	}
+
+	return err
+}
+
+// each calls fn for every i in [0, n) concurrently and waits until all the
+// calls are finished. The first "real" error is returned.
+func (s *scheduler) each(n int, fn func(i int) error) error {
+	errs := make([]error, n)
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			errs[i] = fn(i)
+			if errs[i] != nil {
+				s.fail()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	var firstErr error
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if err != errNotScheduled {
+			return err
+		}
+
+		firstErr = err
+	}
+
+	return firstErr
+}
+
+func (s *scheduler) fail() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failed = !s.keepGoing
+}
+
+func (s *scheduler) hasFailed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.failed
+}
@@ -0,0 +1,95 @@
+package assembly
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerLimitsNumberOfRunningOperations(t *testing.T) {
+	s := newScheduler(2)
+
+	mu := sync.Mutex{}
+	running, maxRunning, calls := 0, 0, 0
+
+	err := s.each(6, func(i int) error {
+		return s.run(func() error {
+			mu.Lock()
+			running++
+			calls++
+
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			return nil
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 6, calls)
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestSchedulerWithoutConcurrencyRunsOneOperation(t *testing.T) {
+	s := newScheduler(0)
+
+	assert.Equal(t, 1, cap(s.slots))
+}
+
+func TestSchedulerStopsStartingOperationsAfterFailure(t *testing.T) {
+	s := newScheduler(1)
+	failure := errors.New("failure")
+
+	assert.Equal(t, failure, s.run(func() error { return failure }))
+
+	called := false
+	err := s.run(func() error {
+		called = true
+		return nil
+	})
+
+	assert.Equal(t, errNotScheduled, err)
+	assert.False(t, called)
+}
+
+func TestSchedulerKeepsGoingAfterFailure(t *testing.T) {
+	s := newScheduler(1)
+	s.keepGoing = true
+
+	assert.Error(t, s.run(func() error { return errors.New("failure") }))
+
+	called := false
+	err := s.run(func() error {
+		called = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, called)
+}
+
+func TestSchedulerReturnsFailureRatherThanNotScheduledError(t *testing.T) {
+	s := newScheduler(3)
+	failure := errors.New("failure")
+
+	err := s.each(3, func(i int) error {
+		if i == 2 {
+			return failure
+		}
+
+		return errNotScheduled
+	})
+
+	assert.Equal(t, failure, err)
+}
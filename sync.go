@@ -1,75 +1,494 @@
 package assembly
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/molecule-man/stack-assembly/awscf"
 	"github.com/molecule-man/stack-assembly/cli"
 	"github.com/molecule-man/stack-assembly/conf"
 )
 
-func (sa SA) Sync(cfg conf.Config, nonInteractive bool) ([]*awscf.Stack, error) {
-	return sa.syncRecursively(cfg, cfg, nonInteractive)
+// SyncOpts are the options of the stacks synchronization.
+type SyncOpts struct {
+	NonInteractive bool
+
+	// Concurrency is the maximum number of stacks synchronized at the same
+	// time. Only the stacks that don't depend on each other are synchronized
+	// concurrently.
+	Concurrency int
+
+	// JournalPath is the path of the file where the progress of the
+	// synchronization is recorded. If empty, the progress is not recorded.
+	JournalPath string
+
+	// Resume makes the synchronization skip the stacks that are recorded in
+	// the journal as synchronized and that haven't changed since then. It
+	// requires JournalPath.
+	Resume bool
+
+	// OnBusy defines what happens if another operation is in progress on the
+	// stack: either OnBusyFail (default) or OnBusyWait.
+	OnBusy string
+
+	// KeepGoing makes the synchronization continue after a stack fails. Only
+	// the stacks depending on the failed one are skipped.
+	KeepGoing bool
+
+	// NoExecute makes the synchronization register the change sets without
+	// executing them. The change sets are kept under ChangeSetName so that
+	// they can be reviewed and later executed by ExecuteChangeSets.
+	NoExecute bool
+
+	// ChangeSetName is the name of the change sets kept in no-execute mode.
+	// DefaultChangeSetName is used if empty.
+	ChangeSetName string
+
+	// FailOnDrift makes the synchronization of the stack fail if the stack
+	// has drifted from its template. The drift is checked before the change
+	// set is created.
+	FailOnDrift bool
+
+	// OverridePolicy is the stack policy (in json) that is in force instead of
+	// the policy of the updated stacks while they are updated. Change sets
+	// don't support StackPolicyDuringUpdateBody, so the stacks are updated
+	// directly with the input of the reviewed change sets.
+	OverridePolicy string
 }
 
-func (sa SA) syncRecursively(stackCfg conf.Config, root conf.Config, nonInteractive bool) ([]*awscf.Stack, error) {
+// DefaultChangeSetName is the name of the change sets kept in no-execute mode
+// unless another name is configured.
+const DefaultChangeSetName = "stas-pending"
+
+func (opts SyncOpts) changeSetName() string {
+	if opts.ChangeSetName == "" {
+		return DefaultChangeSetName
+	}
+
+	return opts.ChangeSetName
+}
+
+// Values of SyncOpts.OnBusy
+const (
+	OnBusyFail = "fail"
+	OnBusyWait = "wait"
+)
+
+// Sync synchronizes the stacks of the config. If ctx is canceled, no new
+// stacks are synchronized and the user is offered to cancel the updates that
+// are in progress.
+func (sa SA) Sync(ctx context.Context, cfg conf.Config, opts SyncOpts) ([]*awscf.Stack, error) {
+	if opts.Resume && opts.JournalPath == "" {
+		return []*awscf.Stack{}, errors.New("journal path is required to resume the synchronization")
+	}
+
+	if opts.Concurrency > 1 {
+		sa = sa.withLockedOutput()
+	}
+
+	sched := newScheduler(opts.Concurrency)
+	sched.keepGoing = opts.KeepGoing
+
+	action := &syncAction{ctx: ctx, sa: sa, opts: opts, sched: sched}
+
+	// in no-execute mode nothing is synchronized and therefore there is no
+	// progress to record
+	if opts.JournalPath != "" && !opts.NoExecute {
+		j, err := openJournal(opts)
+		if err != nil {
+			return []*awscf.Stack{}, err
+		}
+
+		action.journal = j
+	}
+
+	stacks, err := action.syncRecursively(cfg, []string{})
+
+	if opts.KeepGoing {
+		sa.showSyncSummary(action.results.results)
+
+		interrupted := action.results.count(SyncInterrupted)
+
+		if failed := action.results.count(SyncFailed); failed > 0 {
+			err = fmt.Errorf("%d of %d stacks failed to synchronize", failed, len(action.results.results))
+		} else if interrupted > 0 || (err != nil && ctx.Err() != nil) {
+			err = ErrInterrupted
+		}
+	}
+
+	if opts.NoExecute {
+		sa.showKeptChangeSets(action.results.changeSets)
+	}
+
+	if err != nil {
+		if action.journal != nil {
+			sa.cli.Infof("Progress is saved in %s. Run sync with --resume to skip the synchronized stacks", opts.JournalPath)
+		}
+
+		return stacks, err
+	}
+
+	if action.journal != nil {
+		return stacks, action.journal.remove()
+	}
+
+	return stacks, nil
+}
+
+func openJournal(opts SyncOpts) (*journal, error) {
+	if opts.Resume {
+		return loadJournal(opts.JournalPath)
+	}
+
+	j := newJournal(opts.JournalPath)
+
+	// the journal of the previous run must not be resumed after this run
+	return j, j.remove()
+}
+
+var errNestedStacksFailed = errors.New("synchronization of nested stacks failed")
+
+type syncAction struct {
+	ctx   context.Context
+	sa    SA
+	opts  SyncOpts
+	sched *scheduler
+
+	// journal is nil if the progress is not recorded
+	journal *journal
+
+	results syncResults
+
+	// promptMu prevents prompts of concurrently synchronized stacks from
+	// being mixed up
+	promptMu sync.Mutex
+
+	// the answer to the cancellation prompt applies to all the updates
+	// interrupted at once
+	cancelOnce      sync.Once
+	cancelConfirmed bool
+}
+
+func (a *syncAction) syncRecursively(stackCfg conf.Config, ids []string) ([]*awscf.Stack, error) {
 	syncedStacks := []*awscf.Stack{}
 
-	MustSucceed(stackCfg.Hooks.Pre.Exec())
+	// hooks are executed when the kept change sets are executed
+	if !a.opts.NoExecute {
+		if err := a.sa.runGroupHooks(stackCfg, stackCfg.Hooks.Pre); err != nil {
+			if a.opts.KeepGoing {
+				a.sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name)).Error(err.Error())
+
+				if stackCfg.Body != "" {
+					a.results.add(ids, stackCfg.Name, SyncFailed, err)
+				}
+
+				a.results.skipNested(stackCfg, ids)
+			}
+
+			return syncedStacks, err
+		}
+	}
 
 	if stackCfg.Body != "" {
-		logger := sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name))
+		var stack *awscf.Stack
 
-		logger.Info("Synchronizing template")
+		err := a.sched.run(func() error {
+			var err error
+			stack, err = a.syncStack(stackCfg, ids)
 
-		stack, err := sa.exec(stackCfg, logger, nonInteractive)
+			return err
+		})
 		if err != nil {
+			if a.opts.KeepGoing {
+				a.results.skipNested(stackCfg, ids)
+			}
+
 			return syncedStacks, err
 		}
 
-		for _, r := range stackCfg.Blocked {
-			logger.Infof("Blocking resource %s", r)
+		syncedStacks = []*awscf.Stack{stack}
+	}
+
+	nestedStacks, err := a.syncNested(stackCfg, ids)
+	syncedStacks = append(syncedStacks, nestedStacks...)
+
+	if err != nil || a.opts.NoExecute {
+		return syncedStacks, err
+	}
+
+	return syncedStacks, a.sa.runGroupHooks(stackCfg, stackCfg.Hooks.Post)
+}
+
+func (a *syncAction) syncStack(stackCfg conf.Config, ids []string) (*awscf.Stack, error) {
+	if a.ctx.Err() != nil {
+		a.results.add(ids, stackCfg.Name, SyncSkipped, ErrInterrupted)
+		return stackCfg.Stack(), ErrInterrupted
+	}
+
+	logger := a.sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name))
+
+	logger.Info("Synchronizing template")
+
+	stack, status, err := a.syncTemplate(stackCfg, ids, logger)
+
+	switch {
+	case errors.Is(err, ErrInterrupted):
+		status = SyncInterrupted
+	case err != nil:
+		status = SyncFailed
+
+		if a.opts.KeepGoing {
+			logger.Error(err.Error())
+		}
+	}
+
+	a.results.add(ids, stackCfg.Name, status, err)
+
+	return stack, err
+}
+
+func (a *syncAction) syncTemplate(stackCfg conf.Config, ids []string, logger *cli.Logger) (*awscf.Stack, string, error) {
+	if a.opts.NoExecute {
+		if err := checkPendingOutputs(stackCfg, a.results.keptStackNames()); err != nil {
+			return stackCfg.Stack(), SyncFailed, err
+		}
+	}
+
+	stackCfg, err := stackCfg.ResolveStackOutputs()
+	if err != nil {
+		return stackCfg.Stack(), SyncFailed, err
+	}
+
+	hash, err := stackCfg.Hash()
+	if err != nil {
+		return stackCfg.Stack(), SyncFailed, err
+	}
+
+	if a.journal != nil && a.opts.Resume && a.journal.isComplete(ids, hash) {
+		logger.Info("Stack is synchronized in the previous run and is unchanged since then. Skipping")
+		return stackCfg.Stack(), SyncUnchanged, nil
+	}
+
+	if a.opts.FailOnDrift {
+		if err = a.checkDrift(stackCfg, logger); err != nil {
+			return stackCfg.Stack(), SyncFailed, err
+		}
+	}
+
+	stack, status, err := a.exec(stackCfg, stackCfg.ChangeSet(), logger)
+	if err != nil || status == SyncNotExecuted {
+		return stack, status, err
+	}
+
+	if err = a.protect(stackCfg, stack, logger); err != nil {
+		return stack, status, err
+	}
+
+	if a.journal != nil {
+		return stack, status, a.journal.record(ids, hash)
+	}
+
+	return stack, status, nil
+}
+
+// checkPendingOutputs fails if the parameters of the stack refer to outputs of
+// the stacks which change sets are registered but not executed. The outputs
+// of such stacks are stale until the change sets are executed.
+func checkPendingOutputs(stackCfg conf.Config, pending []string) error {
+	isPending := make(map[string]bool, len(pending))
+	for _, name := range pending {
+		isPending[name] = true
+	}
+
+	for _, name := range stackCfg.ReferredStackNames() {
+		if isPending[name] {
+			return fmt.Errorf("parameters of stack %s refer to outputs of stack %s which has changes that are not "+
+				"executed yet. Synchronize stack %s first", stackCfg.Name, name, name)
+		}
+	}
+
+	return nil
+}
+
+// protect applies the stack policy and reconciles the termination protection
+// of the stack.
+func (a *syncAction) protect(stackCfg conf.Config, stack *awscf.Stack, logger *cli.Logger) error {
+	if err := a.reconcileTerminationProtection(stackCfg, stack, logger); err != nil {
+		return err
+	}
+
+	return a.block(stackCfg, stack, logger)
+}
+
+func (a *syncAction) reconcileTerminationProtection(stackCfg conf.Config, stack *awscf.Stack, logger *cli.Logger) error {
+	if stackCfg.EnableTerminationProtection == nil {
+		return nil
+	}
+
+	info, err := stack.Info()
+	if err != nil {
+		return err
+	}
+
+	enabled := *stackCfg.EnableTerminationProtection
+	if info.TerminationProtection() == enabled {
+		return nil
+	}
+
+	if enabled {
+		logger.Info("Enabling termination protection")
+	} else {
+		logger.Info("Disabling termination protection")
+	}
+
+	return stack.SetTerminationProtection(enabled)
+}
 
-			err = stack.BlockResource(r)
+func (a *syncAction) block(stackCfg conf.Config, stack *awscf.Stack, logger *cli.Logger) error {
+	policy, err := stackCfg.Policy()
+	if err != nil || policy == "" {
+		return err
+	}
+
+	current, err := stack.Policy()
+	if err != nil {
+		return err
+	}
+
+	// the policy is compared in normalized form, so that the formatting of
+	// the stored policy doesn't matter
+	if current != "" {
+		if normalized, nerr := awscf.NormalizePolicy(current); nerr == nil && normalized == policy {
+			return nil
+		}
+	}
+
+	if stackCfg.StackPolicy != "" {
+		logger.Info("Applying stack policy")
+	}
+
+	for _, r := range stackCfg.Blocked {
+		logger.Infof("Blocking resource %s", r)
+	}
+
+	return stack.SetPolicy(policy)
+}
+
+func (a *syncAction) syncNested(stackCfg conf.Config, ids []string) ([]*awscf.Stack, error) {
+	syncedStacks := []*awscf.Stack{}
+
+	// in keep-going mode the nested stacks depending on the failed ones are
+	// skipped
+	skipped := map[string]bool{}
+
+	var nestedErr error
+
+	handleFailure := func(id string, err error) error {
+		if !a.opts.KeepGoing {
+			return err
+		}
+
+		for _, depID := range stackCfg.DependentStackIDs(id) {
+			skipped[depID] = true
+		}
+
+		nestedErr = errNestedStacksFailed
+
+		return nil
+	}
+
+	if a.opts.Concurrency <= 1 {
+		nestedIDs, err := stackCfg.StackIDsSortedByExecOrder()
+		if err != nil {
+			return syncedStacks, err
+		}
+
+		for _, id := range nestedIDs {
+			if skipped[id] {
+				a.results.skip(stackCfg.Stacks[id], appendID(ids, id))
+				continue
+			}
+
+			ss, err := a.syncRecursively(stackCfg.Stacks[id], appendID(ids, id))
 			if err != nil {
-				return syncedStacks, err
+				if err = handleFailure(id, err); err != nil {
+					return syncedStacks, err
+				}
+
+				continue
 			}
+
+			syncedStacks = append(syncedStacks, ss...)
 		}
 
-		syncedStacks = []*awscf.Stack{stack}
+		return syncedStacks, nestedErr
 	}
 
-	nestedStacks, err := stackCfg.StackConfigsSortedByExecOrder()
+	levels, err := stackCfg.StackIDsByExecLevel()
 	if err != nil {
 		return syncedStacks, err
 	}
 
-	for _, nestedStack := range nestedStacks {
-		ss, err := sa.syncRecursively(nestedStack, root, nonInteractive)
-		if err != nil {
+	for _, level := range levels {
+		level := a.withoutSkipped(stackCfg, ids, level, skipped)
+		synced := make([][]*awscf.Stack, len(level))
+		errs := make([]error, len(level))
+
+		err := a.sched.each(len(level), func(i int) error {
+			synced[i], errs[i] = a.syncRecursively(stackCfg.Stacks[level[i]], appendID(ids, level[i]))
+			return errs[i]
+		})
+
+		for _, ss := range synced {
+			syncedStacks = append(syncedStacks, ss...)
+		}
+
+		if err != nil && !a.opts.KeepGoing {
 			return syncedStacks, err
 		}
 
-		syncedStacks = append(syncedStacks, ss...)
+		for i, err := range errs {
+			if err != nil {
+				_ = handleFailure(level[i], err)
+			}
+		}
 	}
 
-	return syncedStacks, stackCfg.Hooks.Post.Exec()
+	return syncedStacks, nestedErr
 }
 
-func (sa SA) exec(stackCfg conf.Config, logger *cli.Logger, nonInteractive bool) (*awscf.Stack, error) {
-	cs := stackCfg.ChangeSet()
+// withoutSkipped filters out the skipped stacks of the level and records them
+// as skipped.
+func (a *syncAction) withoutSkipped(stackCfg conf.Config, ids, level []string, skipped map[string]bool) []string {
+	filtered := make([]string, 0, len(level))
 
-	chSet, err := sa.register(cs, logger)
-	if err == awscf.ErrNoChange {
-		logger.Info("No changes to be synchronized")
-		return cs.Stack(), nil
+	for _, id := range level {
+		if skipped[id] {
+			a.results.skip(stackCfg.Stacks[id], appendID(ids, id))
+			continue
+		}
+
+		filtered = append(filtered, id)
 	}
 
-	if err != nil {
-		return cs.Stack(), err
+	return filtered
+}
+
+func appendID(ids []string, id string) []string {
+	return append(append([]string{}, ids...), id)
+}
+
+func (a *syncAction) exec(stackCfg conf.Config, cs *awscf.ChangeSet, logger *cli.Logger) (*awscf.Stack, string, error) {
+	sa := a.sa
+
+	if a.opts.NoExecute {
+		cs.WithName(a.opts.changeSetName())
 	}
 
 	defer func() {
@@ -78,66 +497,156 @@ func (sa SA) exec(stackCfg conf.Config, logger *cli.Logger, nonInteractive bool)
 		}
 	}()
 
-	logger.Infof("Change set is created: %s", chSet.ID)
+	chSet, err := a.registerRecovering(stackCfg, cs, logger)
 
-	sa.showChanges(chSet.Changes)
+	if err == awscf.ErrNoChange {
+		logger.Info("No changes to be synchronized")
 
-	if !nonInteractive {
-		err = sa.letUserChooseNextAction(cs)
-		if err != nil {
-			return cs.Stack(), err
+		// the failed change set must not be mistaken for the kept one
+		if a.opts.NoExecute {
+			if derr := chSet.Delete(); derr != nil {
+				logger.Warnf("Error while deleting change set: %s", derr.Error())
+			}
 		}
+
+		return cs.Stack(), SyncUnchanged, nil
 	}
 
-	if chSet.IsUpdate {
-		err = stackCfg.Hooks.PreUpdate.Exec()
-	} else {
-		err = stackCfg.Hooks.PreCreate.Exec()
+	if a.ctx.Err() != nil {
+		return cs.Stack(), SyncFailed, ErrInterrupted
 	}
 
 	if err != nil {
-		return cs.Stack(), err
+		return cs.Stack(), SyncFailed, err
 	}
 
-	wait := sa.showEvents(cs.Stack(), logger)
+	// the change set that is neither executed nor kept for review (e.g. the
+	// user quits or a hook fails) is deleted
+	kept := false
+
+	defer func() {
+		if chSet.Executed || kept {
+			return
+		}
+
+		if derr := chSet.Delete(); derr != nil {
+			logger.Warnf("Error while deleting change set: %s", derr.Error())
+		}
+	}()
 
-	err = chSet.Exec()
+	a.promptMu.Lock()
 
-	wait <- true
-	<-wait
+	logger.Infof("Change set is created: %s", chSet.ID)
+
+	sa.showChanges(chSet.Changes)
+
+	if !a.opts.NonInteractive && !a.opts.NoExecute {
+		err = sa.letUserChooseNextAction(cs, chSet.Changes)
+	}
+
+	a.promptMu.Unlock()
 
 	if err != nil {
-		return cs.Stack(), err
+		return cs.Stack(), SyncFailed, err
+	}
+
+	if a.ctx.Err() != nil {
+		return cs.Stack(), SyncFailed, ErrInterrupted
 	}
 
+	if a.opts.NoExecute {
+		logger.Info("Change set is kept for review")
+		a.results.keep(stackCfg.Name, chSet)
+
+		kept = true
+
+		return cs.Stack(), SyncNotExecuted, nil
+	}
+
+	return cs.Stack(), SyncSucceeded, a.execChangeSet(stackCfg, cs.Stack(), chSet, logger)
+}
+
+func (a *syncAction) execChangeSet(
+	stackCfg conf.Config,
+	stack *awscf.Stack,
+	chSet *awscf.ChangeSetHandle,
+	logger *cli.Logger,
+) error {
+	hc := conf.HookContext{Operation: conf.HookOperationCreate, ChangeSetID: chSet.ID}
+	preHooks, postHooks := stackCfg.Hooks.PreCreate, stackCfg.Hooks.PostCreate
+
+	// the stack has no outputs before it's created
+	var currentInfo func() (awscf.StackInfo, error)
+
 	if chSet.IsUpdate {
-		err = stackCfg.Hooks.PostUpdate.Exec()
+		hc.Operation = conf.HookOperationUpdate
+		preHooks, postHooks = stackCfg.Hooks.PreUpdate, stackCfg.Hooks.PostUpdate
+		currentInfo = stack.Info
+	}
+
+	err := runStackHooks(stackCfg, preHooks, hc, currentInfo, logger)
+	if err != nil {
+		return err
+	}
+
+	wait, live := a.watch(stack, chSet)
+
+	if chSet.IsUpdate && a.opts.OverridePolicy != "" {
+		logger.Info("Overriding stack policy during the update")
+		err = chSet.ExecWithPolicy(a.ctx, a.opts.OverridePolicy)
 	} else {
-		err = stackCfg.Hooks.PostCreate.Exec()
+		err = chSet.Exec(a.ctx)
+	}
+	if a.ctx.Err() != nil {
+		// the live view would overwrite the prompt and the messages of the
+		// interruption. The events are shown line by line from now on
+		if live {
+			stopWatching(wait)
+			wait = a.sa.showEvents(stack, logger)
+		}
+
+		err = a.interrupt(stack, chSet, logger)
+	}
+
+	stopWatching(wait)
+
+	if err == ErrInterrupted {
+		return err
 	}
 
 	if err != nil {
-		return cs.Stack(), err
+		err = stack.ExplainFailure(err)
+		runFailureHooks(stackCfg, stack, hc, err, logger)
+
+		return err
+	}
+
+	if err = runStackHooks(stackCfg, postHooks, hc, stack.FreshInfo, logger); err != nil {
+		return err
 	}
 
-	logger.Print(sa.cli.Color.Success("Synchronization is complete"))
+	logger.Print(a.sa.cli.Color.Success("Synchronization is complete"))
 
-	return cs.Stack(), nil
+	return nil
 }
 
-func (sa SA) register(cs *awscf.ChangeSet, logger *cli.Logger) (*awscf.ChangeSetHandle, error) {
-	chSet, err := cs.Register()
+func (a *syncAction) register(cs *awscf.ChangeSet, logger *cli.Logger) (*awscf.ChangeSetHandle, error) {
+	chSet, err := cs.Register(a.ctx)
 
 	if paramerr, ok := err.(*awscf.ParametersMissingError); ok {
+		a.promptMu.Lock()
+
 		logger.Warn(paramerr.Error())
 
 		for _, p := range paramerr.MissingParameters {
-			response, rerr := sa.cli.Ask("Enter %s: ", p)
+			response, rerr := a.sa.cli.Ask("Enter %s: ", p)
 			MustSucceed(rerr)
 			cs.WithParameter(p, response)
 		}
 
-		chSet, err = cs.Register()
+		a.promptMu.Unlock()
+
+		chSet, err = cs.Register(a.ctx)
 	}
 
 	return chSet, err
@@ -151,7 +660,11 @@ func (sa SA) showEvents(stack *awscf.Stack, logger *cli.Logger) chan bool {
 	}
 
 	go func() {
-		writer := cli.NewColWriter(sa.cli.Writer, " ")
+		// the events are rendered into the buffer and written at once so that
+		// the events of the concurrently synchronized stacks don't interleave
+		var block bytes.Buffer
+
+		writer := cli.NewColWriter(&block, " ")
 
 		for {
 			events, err := stack.EventsTrack().FreshEvents()
@@ -165,12 +678,20 @@ func (sa SA) showEvents(stack *awscf.Stack, logger *cli.Logger) chan bool {
 
 			writer.Flush()
 
+			if block.Len() > 0 {
+				if _, err := sa.cli.Writer.Write(block.Bytes()); err != nil {
+					logger.Warnf("got an error while printing stack events: %s", err)
+				}
+
+				block.Reset()
+			}
+
 			select {
 			case <-wait:
 				wait <- true
 				return
 			default:
-				time.Sleep(2 * time.Second)
+				time.Sleep(stack.PollInterval())
 			}
 		}
 	}()
@@ -194,18 +715,53 @@ func (sa SA) showChanges(changes []awscf.Change) {
 			}
 
 			repl := sa.cli.Color.Success(fmt.Sprintf("%t", c.ReplacementNeeded))
-			if c.ReplacementNeeded {
+
+			switch {
+			case c.ReplacementNeeded:
 				repl = sa.cli.Color.Fail(fmt.Sprintf("%t", c.ReplacementNeeded))
+			case c.Replacement == cloudformation.ReplacementConditional:
+				repl = sa.cli.Color.Warn("conditionally")
 			}
 
 			t.Row(action, c.ResourceType, c.LogicalResourceID, repl)
 		}
 
 		sa.cli.Print(t.Render())
+		sa.showChangeDetails(changes)
 	}
 }
 
-func (sa SA) letUserChooseNextAction(chSet *awscf.ChangeSet) error {
+// showChangeDetails shows which properties of the modified resources are
+// changed and whether the changes require recreation of the resources.
+func (sa SA) showChangeDetails(changes []awscf.Change) {
+	t := cli.NewTable()
+	t.Header("Resource ID", "Target", "Recreation", "Caused by")
+
+	hasDetails := false
+
+	for _, c := range changes {
+		for _, d := range c.Details {
+			hasDetails = true
+
+			recreation := sa.cli.Color.Success(d.RequiresRecreation)
+
+			switch d.RequiresRecreation {
+			case cloudformation.RequiresRecreationAlways:
+				recreation = sa.cli.Color.Fail(d.RequiresRecreation)
+			case cloudformation.RequiresRecreationConditionally:
+				recreation = sa.cli.Color.Warn(d.RequiresRecreation)
+			}
+
+			t.Row(c.LogicalResourceID, d.Target(), recreation, d.Cause())
+		}
+	}
+
+	if hasDetails {
+		sa.cli.Print(t.Render())
+	}
+}
+
+func (sa SA) letUserChooseNextAction(chSet *awscf.ChangeSet, changes []awscf.Change) error {
 	var actionErr error
 
 	continueSync := false
@@ -223,12 +779,17 @@ func (sa SA) letUserChooseNextAction(chSet *awscf.ChangeSet) error {
 				Description:   "[d]iff",
 				TriggerInputs: []string{"d", "diff"},
 				Action: func() {
-					diff, derr := awscf.ChSetDiff{Color: sa.cli.Color}.Diff(chSet)
+					differ := awscf.ChSetDiff{Color: sa.cli.Color}
+					diff, derr := differ.Diff(chSet)
 					actionErr = derr
 
 					if derr == nil {
 						sa.cli.Print(diff)
 					}
+
+					if details := differ.Changes(changes); derr == nil && details != "" {
+						sa.cli.Print(details)
+					}
 				},
 			},
 			{
@@ -0,0 +1,150 @@
+package assembly
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/molecule-man/stack-assembly/cli"
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// Outcomes of the stack synchronization.
+const (
+	SyncSucceeded = "succeeded"
+	SyncFailed    = "failed"
+	SyncSkipped   = "skipped"
+	SyncUnchanged = "unchanged"
+
+	// SyncInterrupted is the outcome of the stack which synchronization is
+	// interrupted by the user.
+	SyncInterrupted = "interrupted"
+
+	// SyncNotExecuted is the outcome of the synchronization in no-execute
+	// mode: the change set is registered and kept for review.
+	SyncNotExecuted = "not executed"
+)
+
+// SyncResult is the outcome of the synchronization of a single stack.
+type SyncResult struct {
+	// IDs is the path of IDs leading to the stack config.
+	IDs    []string
+	Name   string
+	Status string
+	Err    error
+}
+
+type syncResults struct {
+	mu      sync.Mutex
+	results []SyncResult
+
+	// changeSets are the change sets kept in no-execute mode
+	changeSets []keptChangeSet
+}
+
+type keptChangeSet struct {
+	stackName string
+	id        string
+	url       string
+}
+
+func (r *syncResults) add(ids []string, name, status string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.results = append(r.results, SyncResult{IDs: ids, Name: name, Status: status, Err: err})
+}
+
+// skip marks the stack and all its nested stacks as skipped.
+func (r *syncResults) skip(stackCfg conf.Config, ids []string) {
+	if stackCfg.Body != "" {
+		r.add(ids, stackCfg.Name, SyncSkipped, nil)
+	}
+
+	r.skipNested(stackCfg, ids)
+}
+
+// skipNested marks all the nested stacks of the stack as skipped.
+func (r *syncResults) skipNested(stackCfg conf.Config, ids []string) {
+	nestedIDs, err := stackCfg.StackIDsSortedByExecOrder()
+	if err != nil {
+		return
+	}
+
+	for _, id := range nestedIDs {
+		r.skip(stackCfg.Stacks[id], appendID(ids, id))
+	}
+}
+
+func (r *syncResults) keep(stackName string, chSet *awscf.ChangeSetHandle) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.changeSets = append(r.changeSets, keptChangeSet{stackName: stackName, id: chSet.ID, url: chSet.ConsoleURL()})
+}
+
+// keptStackNames returns the names of the stacks which change sets are kept.
+func (r *syncResults) keptStackNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, len(r.changeSets))
+	for i, cs := range r.changeSets {
+		names[i] = cs.stackName
+	}
+
+	return names
+}
+
+func (r *syncResults) count(status string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+
+	for _, res := range r.results {
+		if res.Status == status {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (sa SA) showSyncSummary(results []SyncResult) {
+	t := cli.NewTable()
+	t.Header("ID", "Stack", "Status")
+
+	for _, res := range results {
+		status := sa.cli.Color.Neutral(res.Status)
+
+		switch res.Status {
+		case SyncSucceeded:
+			status = sa.cli.Color.Success(res.Status)
+		case SyncFailed:
+			status = sa.cli.Color.Fail(res.Status)
+		case SyncSkipped, SyncInterrupted:
+			status = sa.cli.Color.Warn(res.Status)
+		}
+
+		t.Row(strings.Join(res.IDs, " "), res.Name, status)
+	}
+
+	sa.cli.Print(t.Render())
+}
+
+func (sa SA) showKeptChangeSets(changeSets []keptChangeSet) {
+	if len(changeSets) == 0 {
+		sa.cli.Info("No change sets are kept")
+		return
+	}
+
+	t := cli.NewTable()
+	t.Header("Stack", "Change set", "Console")
+
+	for _, cs := range changeSets {
+		t.Row(cs.stackName, cs.id, cs.url)
+	}
+
+	sa.cli.Print(t.Render())
+}
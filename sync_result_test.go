@@ -0,0 +1,25 @@
+package assembly
+
+import (
+	"testing"
+
+	"github.com/molecule-man/stack-assembly/awscf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeptChangeSetsAreShownWithArnAndConsoleURL(t *testing.T) {
+	chSet := &awscf.ChangeSetHandle{
+		ID:      "arn:aws:cloudformation:eu-west-1:123456789012:changeSet/stas-pending/0a1b",
+		StackID: "arn:aws:cloudformation:eu-west-1:123456789012:stack/app/9c8d",
+	}
+
+	results := syncResults{}
+	results.keep("app", chSet)
+
+	sa, out := testSA()
+	sa.showKeptChangeSets(results.changeSets)
+
+	assert.Contains(t, out.String(), "app")
+	assert.Contains(t, out.String(), chSet.ID)
+	assert.Contains(t, out.String(), chSet.ConsoleURL())
+}
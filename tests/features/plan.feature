@@ -0,0 +1,51 @@
+Feature: stas plan and apply
+    Background:
+        Given file "cfg.yaml" exists:
+            """
+            stacks:
+                stack1:
+                    name: stastest-%scenarioid%
+                    path: tpls/stack1.yml
+                    tags:
+                        STAS_TEST: '%featureid%'
+
+            """
+        And file "tpls/stack1.yml" exists:
+            """
+            Resources:
+                Cluster:
+                    Type: AWS::ECS::Cluster
+                    Properties:
+                        ClusterName: stastest-%scenarioid%
+            """
+
+    @nomock
+    Scenario: apply executes the planned change sets
+        Given I successfully run "plan -c cfg.yaml --nocolor -o %testdir%/plan.json"
+        And output should contain:
+            """
+            Plan of 1 change set(s) is saved in %testdir%/plan.json
+            """
+        And stack "stastest-%scenarioid%" should have status "REVIEW_IN_PROGRESS"
+        When I successfully run "apply -c cfg.yaml --no-interaction --nocolor %testdir%/plan.json"
+        Then output should contain:
+            """
+            [stastest-%scenarioid%] Executing change set
+            """
+        And stack "stastest-%scenarioid%" should have status "CREATE_COMPLETE"
+
+    @nomock
+    Scenario: apply rejects the plan if the template has changed
+        Given I successfully run "plan -c cfg.yaml --nocolor -o %testdir%/plan.json"
+        And I modify file "tpls/stack1.yml":
+            """
+            Resources:
+                Cluster:
+                    Type: AWS::ECS::Cluster
+            """
+        When I run "apply -c cfg.yaml --no-interaction --nocolor %testdir%/plan.json"
+        Then exit code should not be zero
+        And error contains:
+            """
+            plan can't be applied to the stack stastest-%scenarioid%: template has changed since the plan was made
+            """
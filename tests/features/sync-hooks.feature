@@ -1,5 +1,6 @@
 Feature: stas sync with hooks
 
+    @nomock
     Scenario: sync executes all the possible hooks
         Given file "cfg.yaml" exists:
             """
@@ -64,3 +65,79 @@ Feature: stas sync with hooks
             stack post executed
             root post executed
             """
+
+    @nomock
+    Scenario: sync passes the stack operation to the shell hooks
+        Given file "cfg.yaml" exists:
+            """
+            stacks:
+              stack1:
+                name: stastest-hooks-%scenarioid%
+                path: tpls/stack1.yml
+                tags:
+                  STAS_TEST: '%featureid%'
+                hooks:
+                  postcreate:
+                    - echo "$STAS_OPERATION $STAS_STACK_NAME" > %testdir%/hooks.log
+                  postupdate:
+                    - echo "$STAS_OPERATION $STAS_STACK_NAME" >> %testdir%/hooks.log
+            """
+        And file "tpls/stack1.yml" exists:
+            """
+            Resources:
+              EcsCluster:
+                Type: AWS::ECS::Cluster
+                Properties:
+                    ClusterName: !Ref AWS::StackName
+            """
+        When I successfully run "sync -c cfg.yaml --no-interaction"
+        And I modify file "tpls/stack1.yml":
+            """
+            Resources:
+              EcsCluster:
+                Type: AWS::ECS::Cluster
+                Properties:
+                    ClusterName: !Sub "${AWS::StackName}-modify"
+            """
+        And I successfully run "sync -c cfg.yaml --no-interaction"
+        Then file "hooks.log" should contain exactly:
+            """
+            create stastest-hooks-%scenarioid%
+            update stastest-hooks-%scenarioid%
+            """
+
+    @nomock
+    Scenario: sync executes the hooks defined as objects
+        Given file "cfg.yaml" exists:
+            """
+            stacks:
+              stack1:
+                name: stastest-hooks-%scenarioid%
+                path: tpls/stack1.yml
+                tags:
+                  STAS_TEST: '%featureid%'
+                hooks:
+                  precreate:
+                    - cmd: ["sh", "-c", "echo $STAGE $(basename $(pwd)) > ../hooks.log"]
+                      dir: %testdir%/tpls
+                      env:
+                        STAGE: dev
+                  postcreate:
+                    - shell: echo "$STAS_OPERATION $STAGE" >> %testdir%/hooks.log
+                      env:
+                        STAGE: dev
+            """
+        And file "tpls/stack1.yml" exists:
+            """
+            Resources:
+              EcsCluster:
+                Type: AWS::ECS::Cluster
+                Properties:
+                    ClusterName: !Ref AWS::StackName
+            """
+        When I successfully run "sync -c cfg.yaml --no-interaction"
+        Then file "hooks.log" should contain exactly:
+            """
+            dev tpls
+            create dev
+            """
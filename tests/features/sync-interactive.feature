@@ -40,7 +40,7 @@ Feature: stas sync in interactive mode
         Then launched program should exit with zero status
         And stack "stastest-%scenarioid%" should have status "CREATE_COMPLETE"
 
-    @short
+    @short @nomock
     Scenario: reject syncing
         Given I launched "sync -c cfg.yaml"
         And terminal shows:
@@ -0,0 +1,54 @@
+Feature: stas sync --keep-going
+
+    @nomock
+    Scenario: sync keeps going after the failed stack
+        Given file "cfg.yaml" exists:
+            """
+            stacks:
+              broken:
+                name: stastest-fail1-%scenarioid%
+                path: tpls/broken.yml
+                tags:
+                  STAS_TEST: '%featureid%'
+              dependent:
+                name: stastest-dependent-%scenarioid%
+                path: tpls/stack1.yml
+                dependsOn: [broken]
+                tags:
+                  STAS_TEST: '%featureid%'
+              stack1:
+                name: stastest-1-%scenarioid%
+                path: tpls/stack1.yml
+                tags:
+                  STAS_TEST: '%featureid%'
+            """
+        And file "tpls/broken.yml" exists:
+            """
+            Resources:
+              "Fn::Transform":
+                - Name: 'AWS::Include'
+                  Parameters:
+                    Location: 's3://non-existent-bucket-%scenarioid%/non-existent-tpl.yml'
+            """
+        And file "tpls/stack1.yml" exists:
+            """
+            Resources:
+                Cluster:
+                    Type: AWS::ECS::Cluster
+                    Properties:
+                        ClusterName: !Ref AWS::StackName
+            """
+        When I run "sync -c cfg.yaml --no-interaction --nocolor --keep-going --journal %testdir%/journal.json"
+        Then exit code should not be zero
+        And error contains:
+            """
+            1 of 3 stacks failed to synchronize
+            """
+        And output should contain:
+            """
+            | stack1    | stastest-1-%scenarioid%         | succeeded |
+            | broken    | stastest-fail1-%scenarioid%     | failed    |
+            | dependent | stastest-dependent-%scenarioid% | skipped   |
+            """
+        And stack "stastest-1-%scenarioid%" should have status "CREATE_COMPLETE"
+        And stack "stastest-fail1-%scenarioid%" should have status "REVIEW_IN_PROGRESS"
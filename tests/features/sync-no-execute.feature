@@ -0,0 +1,35 @@
+Feature: stas sync --no-execute
+    Background:
+        Given file "cfg.yaml" exists:
+            """
+            stacks:
+                stack1:
+                    name: stastest-%scenarioid%
+                    path: tpls/stack1.yml
+                    tags:
+                        STAS_TEST: '%featureid%'
+
+            """
+        And file "tpls/stack1.yml" exists:
+            """
+            Resources:
+                Cluster:
+                    Type: AWS::ECS::Cluster
+                    Properties:
+                        ClusterName: stastest-%scenarioid%
+            """
+
+    @nomock
+    Scenario: execute the change sets kept by sync
+        Given I successfully run "sync -c cfg.yaml --no-interaction --nocolor --no-execute"
+        And output should contain:
+            """
+            [stastest-%scenarioid%] Change set is kept for review
+            """
+        And stack "stastest-%scenarioid%" should have status "REVIEW_IN_PROGRESS"
+        When I successfully run "execute-change-sets -c cfg.yaml --no-interaction --nocolor"
+        Then output should contain:
+            """
+            [stastest-%scenarioid%] Executing change set
+            """
+        And stack "stastest-%scenarioid%" should have status "CREATE_COMPLETE"
@@ -0,0 +1,22 @@
+package assembly
+
+import (
+	"fmt"
+
+	"github.com/molecule-man/stack-assembly/conf"
+)
+
+// Unblock discards the blocking of the resource of the stack. The resource is
+// blocked again by the next synchronization unless it's removed from the
+// blocked resources of the config.
+func (sa SA) Unblock(stackCfg conf.Config, resource string) error {
+	logger := sa.cli.PrefixedLogger(fmt.Sprintf("[%s] ", stackCfg.Name))
+
+	if err := stackCfg.Stack().UnblockResource(resource); err != nil {
+		return err
+	}
+
+	logger.Infof("Resource %s is unblocked", resource)
+
+	return nil
+}